@@ -3,20 +3,32 @@ package main
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	nurl "net/url"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/urfave/cli/v2"
 
+	"github.com/tagirmukail/ldtester/internal/cluster"
 	"github.com/tagirmukail/ldtester/internal/config"
 	"github.com/tagirmukail/ldtester/internal/logger"
+	"github.com/tagirmukail/ldtester/internal/outputs"
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
 	"github.com/tagirmukail/ldtester/internal/router"
+	"github.com/tagirmukail/ldtester/internal/selfmon"
+	"github.com/tagirmukail/ldtester/internal/store"
 	"github.com/tagirmukail/ldtester/internal/tester"
+	"github.com/tagirmukail/ldtester/internal/tracing"
+	"github.com/tagirmukail/ldtester/internal/tui"
 	"github.com/tagirmukail/ldtester/internal/url_item"
 )
 
@@ -25,6 +37,47 @@ const (
 	loadCSVFlagName = "loadcsv"
 	urlFlagName     = "url"
 	methodFlagName  = "method"
+	labelFlagName   = "label"
+	tuiFlagName     = "tui"
+	groupByFlagName = "group-by"
+
+	trialsFlagName      = "trials"
+	trialsPauseFlagName = "trials-pause"
+	cvThresholdFlagName = "cv-threshold"
+
+	thinkTimeFlagName        = "think-time"
+	thinkTimeFileFlagName    = "think-time-file"
+	pacingFlagName           = "pacing"
+	mixFlagName              = "mix"
+	hostBudgetFlagName       = "host-budget"
+	rateLimitFlagName        = "rate-limit"
+	rateLimitRetriesFlagName = "rate-limit-retries"
+	tlsVerifyFlagName        = "tls-verify"
+	resolveFlagName          = "resolve"
+	dnsServerFlagName        = "dns-server"
+	dnsCacheFlagName         = "dns-cache"
+	dnsRoundRobinFlagName    = "dns-round-robin"
+	dnsPreferFlagName        = "dns-prefer"
+	proxyFlagName            = "proxy"
+	proxyFromEnvFlagName     = "proxy-from-env"
+	localAddrFlagName        = "local-addr"
+	localAddrModeFlagName    = "local-addr-mode"
+	unixSocketFlagName       = "unix-socket"
+	http2FlagName            = "http2"
+	h2cFlagName              = "h2c"
+	h2MaxInFlightFlagName    = "h2-max-in-flight"
+	h2ConnectionsFlagName    = "h2-connections"
+	cookiesFlagName          = "cookies"
+	cookiesSharedFlagName    = "cookies-shared"
+	cookiesFileFlagName      = "cookies-file"
+	cookiesClearFlagName     = "cookies-clear"
+	redirectsFlagName        = "redirects"
+	redirectMaxHopsFlagName  = "redirect-max-hops"
+	responseBodyFlagName     = "response-body"
+
+	coordinatorFlagName = "coordinator"
+	agentNameFlagName   = "name"
+	agentTokenFlagName  = "token"
 )
 
 func main() {
@@ -51,7 +104,7 @@ func main() {
 						Name:    loadCSVFlagName,
 						Aliases: []string{"f"},
 						Usage: "Get from this csv file urls and run load test for all. File data format:" +
-							"url",
+							"url[,weight of the mix]",
 					},
 					&cli.StringFlag{
 						Name:    urlFlagName,
@@ -63,9 +116,176 @@ func main() {
 						Aliases: []string{"m"},
 						Usage:   "Method for load test url",
 					},
+					&cli.StringFlag{
+						Name:    labelFlagName,
+						Aliases: []string{"l"},
+						Usage:   "Label of the run in the runs history, for example deployment version",
+					},
+					&cli.StringFlag{
+						Name:    groupByFlagName,
+						Aliases: []string{"g"},
+						Usage:   "Group the report by url, host or all",
+						Value:   string(tester.GroupByURL),
+					},
+					&cli.IntFlag{
+						Name:  trialsFlagName,
+						Usage: "Repeat the full load test this number of times and report means with 95% confidence intervals",
+						Value: 1,
+					},
+					&cli.DurationFlag{
+						Name:  trialsPauseFlagName,
+						Usage: "Pause between trials, for example 30s",
+					},
+					&cli.Float64Flag{
+						Name:  cvThresholdFlagName,
+						Usage: "Flag urls whose results of trials vary beyond this coefficient of variation, percent",
+						Value: 10,
+					},
+					&cli.StringFlag{
+						Name: thinkTimeFlagName,
+						Usage: "Pause of the virtual user after its request in ms: constant:500, uniform:100:900, " +
+							"normal:500:100 (mean:stddev), exponential:500 (mean)",
+					},
+					&cli.StringFlag{
+						Name:  thinkTimeFileFlagName,
+						Usage: "File of recorded think times, one value in ms per line",
+					},
+					&cli.DurationFlag{
+						Name:  pacingFlagName,
+						Usage: "Target duration of the virtual user iteration of the request and the think time, for example 2s",
+					},
+					&cli.BoolFlag{
+						Name:  mixFlagName,
+						Usage: "Distribute one load profile across urls by their weights from the second column of the csv file",
+					},
+					&cli.BoolFlag{
+						Name:  hostBudgetFlagName,
+						Usage: "Urls of the same host share one load profile and one transport, report the capacity verdict of every host",
+					},
+					&cli.StringFlag{
+						Name:  rateLimitFlagName,
+						Usage: "Handling of responses 429 and 503: count, ceiling, backoff (by Retry-After) or retry",
+					},
+					&cli.IntFlag{
+						Name:  rateLimitRetriesFlagName,
+						Usage: "Max retries of the rate limited request in the retry mode",
+					},
+					&cli.BoolFlag{
+						Name:  tlsVerifyFlagName,
+						Usage: "Verify certificates of targets",
+					},
+					&cli.StringSliceFlag{
+						Name:  resolveFlagName,
+						Usage: "Resolve the host and port to addresses like curl: host:port:addr[,addr], can be repeated",
+					},
+					&cli.StringFlag{
+						Name:  dnsServerFlagName,
+						Usage: "DNS server host:port instead of the system one",
+					},
+					&cli.BoolFlag{
+						Name:  dnsCacheFlagName,
+						Usage: "Resolve addresses of hosts once",
+					},
+					&cli.BoolFlag{
+						Name:  dnsRoundRobinFlagName,
+						Usage: "Distribute connections across all addresses of the host",
+					},
+					&cli.StringFlag{
+						Name:  dnsPreferFlagName,
+						Usage: "Try ipv4 or ipv6 addresses first",
+					},
+					&cli.StringFlag{
+						Name:  proxyFlagName,
+						Usage: "Proxy url of requests: http, https, socks5 or socks5h scheme with optional user:password",
+					},
+					&cli.BoolFlag{
+						Name:  proxyFromEnvFlagName,
+						Usage: "Use proxies of HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables",
+					},
+					&cli.StringSliceFlag{
+						Name:  localAddrFlagName,
+						Usage: "Local ip address of target connections, can be repeated",
+					},
+					&cli.StringFlag{
+						Name:  localAddrModeFlagName,
+						Usage: "Rotation of local addresses: round_robin by connections or per_vu",
+					},
+					&cli.StringFlag{
+						Name:  unixSocketFlagName,
+						Usage: "Path of the unix socket of targets, urls set the Host header only",
+					},
+					&cli.BoolFlag{
+						Name:  http2FlagName,
+						Usage: "Use http/2 of https urls negotiated by ALPN",
+					},
+					&cli.BoolFlag{
+						Name:  h2cFlagName,
+						Usage: "Use http/2 with prior knowledge of http urls",
+					},
+					&cli.IntFlag{
+						Name:  h2MaxInFlightFlagName,
+						Usage: "Max in-flight http/2 requests of the connection if http/2 connections are set, of the url otherwise",
+					},
+					&cli.IntFlag{
+						Name:  h2ConnectionsFlagName,
+						Usage: "Http/2 connections of the host",
+					},
+					&cli.BoolFlag{
+						Name:  cookiesFlagName,
+						Usage: "Keep cookies of every virtual user in its jar",
+					},
+					&cli.BoolFlag{
+						Name:  cookiesSharedFlagName,
+						Usage: "Keep cookies of all virtual users in one jar",
+					},
+					&cli.StringFlag{
+						Name:  cookiesFileFlagName,
+						Usage: "Preload cookies of the netscape format to every jar",
+					},
+					&cli.BoolFlag{
+						Name:  cookiesClearFlagName,
+						Usage: "Clear the jar of the virtual user before its iteration",
+					},
+					&cli.StringFlag{
+						Name:  redirectsFlagName,
+						Usage: "Redirect policy: follow, none, same_host",
+					},
+					&cli.IntFlag{
+						Name:  redirectMaxHopsFlagName,
+						Usage: "Max followed redirects of the request",
+					},
+					&cli.StringFlag{
+						Name:  responseBodyFlagName,
+						Usage: "Receiving of response bodies: discard (read to the end), skip (closed unread)",
+					},
+					&cli.BoolFlag{
+						Name:  tuiFlagName,
+						Usage: "Show full-screen dashboard with keyboard controls instead of progress bars",
+					},
 				},
 				Action: runLoad,
 			},
+			{
+				Name:  "agent",
+				Usage: "Run the agent of distributed load tests of the coordinator server",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    coordinatorFlagName,
+						Aliases: []string{"a"},
+						Usage:   "Coordinator server address, for example http://localhost:8000",
+					},
+					&cli.StringFlag{
+						Name:    agentNameFlagName,
+						Aliases: []string{"n"},
+						Usage:   "Agent name in the coordinator agents list, hostname by default",
+					},
+					&cli.StringFlag{
+						Name:  agentTokenFlagName,
+						Usage: "Shared token of the coordinator and agents, Cluster.Token by default",
+					},
+				},
+				Action: runAgent,
+			},
 		},
 	}
 
@@ -83,34 +303,346 @@ func runLoad(c *cli.Context) error {
 
 	cfg := initConfig(c.String(configFlagName))
 
-	fmt.Printf("===============\n%+v\n===============\n", cfg)
+	fmt.Printf("===============\n%+v\n===============\n", cfg.Redacted())
 
 	fmt.Println("setup configuration done.")
 
-	log := logger.New(ctx, cfg.LogLevel, os.Stdout)
-
 	csvFile := c.String(loadCSVFlagName)
 	url := c.String(urlFlagName)
 	method := c.String(methodFlagName)
+	trials := c.Int(trialsFlagName)
 
 	items, err := loadTestItems(csvFile, url)
 	if err != nil {
 		return err
 	}
 
-	conf := tester.FromGlobalConfig(cfg.LoadTest)
+	group, err := tester.ParseGroup(c.String(groupByFlagName))
+	if err != nil {
+		return err
+	}
+
+	if trials < 1 {
+		return fmt.Errorf("invalid trials count %d", trials)
+	}
+
+	var (
+		dashboard *tui.Dashboard
+		logOutput io.Writer = os.Stdout
+	)
+
+	if c.Bool(tuiFlagName) {
+		if trials > 1 {
+			return errors.New("dashboard can't be used with several trials")
+		}
+
+		dashboard, err = tui.New(itemsKeys(items))
+		if err != nil {
+			return err
+		}
+
+		logOutput = dashboard
+	}
+
+	log := logger.New(ctx, cfg.LogLevel, logOutput)
+
+	conf, err := tester.FromGlobalConfig(cfg.LoadTest)
+	if err != nil {
+		return err
+	}
 
 	if method != "" {
 		conf.Method = method
 	}
 
-	t := tester.New(ctx, cancel, log, conf, items)
+	if c.IsSet(thinkTimeFlagName) {
+		conf.ThinkTime, err = tester.ParseThinkTime(c.String(thinkTimeFlagName))
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.IsSet(thinkTimeFileFlagName) {
+		samples, err := tester.LoadThinkTimes(c.String(thinkTimeFileFlagName))
+		if err != nil {
+			return err
+		}
+
+		conf.ThinkTime = tester.ThinkTime{Distribution: tester.ThinkTimeRecorded, Samples: samples}
+
+		err = conf.ThinkTime.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.IsSet(pacingFlagName) {
+		conf.Pacing = c.Duration(pacingFlagName)
+	}
+
+	if c.Bool(mixFlagName) {
+		conf.Mix = true
+	}
+
+	if c.Bool(hostBudgetFlagName) {
+		conf.HostBudget = true
+	}
+
+	if c.IsSet(rateLimitFlagName) {
+		conf.RateLimit.Mode = c.String(rateLimitFlagName)
+	}
+
+	if c.IsSet(rateLimitRetriesFlagName) {
+		conf.RateLimit.MaxRetries = c.Int(rateLimitRetriesFlagName)
+	}
+
+	err = conf.RateLimit.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.IsSet(tlsVerifyFlagName) {
+		verify := c.Bool(tlsVerifyFlagName)
+		conf.TLS.Verify = &verify
+	}
+
+	if c.IsSet(proxyFlagName) {
+		conf.Proxy = proxyconf.Options{URL: c.String(proxyFlagName)}
+	}
+
+	if c.Bool(proxyFromEnvFlagName) {
+		conf.Proxy = proxyconf.Options{FromEnvironment: true}
+	}
+
+	if c.IsSet(resolveFlagName) {
+		conf.Resolver.Resolve = c.StringSlice(resolveFlagName)
+	}
+
+	if c.IsSet(dnsServerFlagName) {
+		conf.Resolver.Server = c.String(dnsServerFlagName)
+	}
+
+	if c.IsSet(dnsCacheFlagName) {
+		conf.Resolver.Cache = c.Bool(dnsCacheFlagName)
+	}
+
+	if c.IsSet(dnsRoundRobinFlagName) {
+		conf.Resolver.RoundRobin = c.Bool(dnsRoundRobinFlagName)
+	}
+
+	if c.IsSet(dnsPreferFlagName) {
+		conf.Resolver.Prefer = c.String(dnsPreferFlagName)
+	}
+
+	err = conf.Resolver.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.IsSet(localAddrFlagName) {
+		conf.Dial.LocalAddrs = c.StringSlice(localAddrFlagName)
+	}
+
+	if c.IsSet(localAddrModeFlagName) {
+		conf.Dial.LocalAddrMode = c.String(localAddrModeFlagName)
+	}
+
+	if c.IsSet(unixSocketFlagName) {
+		conf.Dial.UnixSocket = c.String(unixSocketFlagName)
+	}
+
+	err = conf.Dial.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.Bool(http2FlagName) {
+		conf.UseHTTP2 = true
+	}
+
+	if c.Bool(h2cFlagName) {
+		conf.HTTP2.H2C = true
+	}
+
+	if c.IsSet(h2MaxInFlightFlagName) {
+		conf.HTTP2.MaxInFlight = c.Int(h2MaxInFlightFlagName)
+	}
+
+	if c.IsSet(h2ConnectionsFlagName) {
+		conf.HTTP2.Connections = c.Int(h2ConnectionsFlagName)
+	}
 
-	t.Run()
+	err = conf.HTTP2.Validate()
+	if err != nil {
+		return err
+	}
 
-	report := t.Report()
+	err = tester.ValidateTransport(conf, items)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool(cookiesFlagName) {
+		conf.Cookies.Enabled = true
+	}
+
+	if c.Bool(cookiesSharedFlagName) {
+		conf.Cookies.Enabled, conf.Cookies.Shared = true, true
+	}
+
+	if c.IsSet(cookiesFileFlagName) {
+		conf.Cookies.Enabled, conf.Cookies.File = true, c.String(cookiesFileFlagName)
+	}
+
+	if c.Bool(cookiesClearFlagName) {
+		conf.Cookies.Enabled, conf.Cookies.ClearPerIteration = true, true
+	}
 
-	formattedOutputReport(report)
+	err = conf.Cookies.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.IsSet(redirectsFlagName) {
+		conf.Redirects.Policy = c.String(redirectsFlagName)
+	}
+
+	if c.IsSet(redirectMaxHopsFlagName) {
+		conf.Redirects.MaxHops = c.Int(redirectMaxHopsFlagName)
+	}
+
+	err = conf.Redirects.Validate()
+	if err != nil {
+		return err
+	}
+
+	if c.IsSet(responseBodyFlagName) {
+		conf.ResponseBody = c.String(responseBodyFlagName)
+	}
+
+	err = tester.ValidateResponseBody(conf.ResponseBody)
+	if err != nil {
+		return err
+	}
+
+	tracer := tracing.New(cfg.Tracing, log)
+	defer tracer.Shutdown()
+
+	var observers []tester.Observer
+
+	if cfg.Outputs.Enabled() {
+		pusher, err := outputs.New(cfg.Outputs, log)
+		if err != nil {
+			return err
+		}
+
+		observers = append(observers, pusher)
+
+		pusher.Start()
+		defer pusher.Stop()
+	}
+
+	reports := make([]map[string]tester.Item, 0, trials)
+
+	for trial := 1; trial <= trials; trial++ {
+		if trials > 1 {
+			if trial > 1 && c.Duration(trialsPauseFlagName) > 0 {
+				fmt.Printf("pause %s before the next trial...\n", c.Duration(trialsPauseFlagName))
+
+				select {
+				case <-time.After(c.Duration(trialsPauseFlagName)):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+
+			fmt.Printf("trial %d of %d...\n", trial, trials)
+		}
+
+		startedAt := time.Now()
+
+		trialCtx, trialCancel := context.WithCancel(ctx)
+
+		t := tester.New(trialCtx, trialCancel, log, conf, items)
+
+		t.SetTracer(tracer)
+
+		if cfg.SelfMonitor.Enabled {
+			t.SetMonitor(selfmon.New(selfmon.FromConfig(cfg.SelfMonitor), log))
+		}
+
+		for _, o := range observers {
+			t.AddObserver(o)
+		}
+
+		err = runTester(t, dashboard)
+		trialCancel()
+
+		if err != nil {
+			return err
+		}
+
+		report := t.Report()
+
+		formattedOutputReport(tester.GroupBy(report, group))
+
+		if conf.Mix {
+			formattedOutputMix(tester.MixReport(items, report), tester.GroupBy(report, tester.GroupByAll))
+		}
+
+		formattedOutputVerdicts(t.Verdicts())
+
+		formattedOutputGenerator(t.GeneratorReport())
+
+		reports = append(reports, tester.GroupBy(report, group))
+
+		// the report is printed already, so the run isn't failed if the history file is locked by the server
+		err = saveRun(cfg.Store, &store.Run{
+			Label:      c.String(labelFlagName),
+			StartedAt:  startedAt,
+			FinishedAt: time.Now(),
+			Config:     conf,
+			Items:      items,
+			Report:     reportByURL(report),
+			Generator:  t.GeneratorReport(),
+			Verdicts:   t.Verdicts(),
+		})
+		if err != nil {
+			log.WithError(err).WithField("path", cfg.Store.Path).Warn("save run to the runs history failed")
+		}
+	}
+
+	if trials > 1 {
+		formattedOutputTrials(tester.SummarizeTrials(reports, c.Float64(cvThresholdFlagName)/100))
+	}
+
+	return nil
+}
+
+// runTester runs the load test with progress bars or with the dashboard if it is not nil
+func runTester(t *tester.Tester, dashboard *tui.Dashboard) error {
+	if dashboard == nil {
+		t.Run()
+
+		return nil
+	}
+
+	t.AddObserver(dashboard)
+	t.DisableProgressBar()
+
+	done := make(chan struct{})
+	go func() {
+		t.Run()
+		close(done)
+	}()
+
+	err := dashboard.Run(t, done)
+	if err != nil {
+		t.Stop()
+		<-done
+
+		return err
+	}
 
 	return nil
 }
@@ -123,7 +655,7 @@ func runServer(c *cli.Context) error {
 
 	cfg := initConfig(c.String(configFlagName))
 
-	fmt.Printf("===============\n%+v\n===============\n", cfg)
+	fmt.Printf("===============\n%+v\n===============\n", cfg.Redacted())
 
 	fmt.Println("setup configuration done.")
 
@@ -131,9 +663,27 @@ func runServer(c *cli.Context) error {
 
 	fmt.Println("setup router...")
 
+	tracer := tracing.New(cfg.Tracing, log)
+	defer tracer.Shutdown()
+
 	options := &router.Options{
-		Cfg: &cfg,
-		Log: log,
+		Cfg:    &cfg,
+		Log:    log,
+		Tracer: tracer,
+	}
+
+	if cfg.Store.Path != "" {
+		s, err := store.Open(cfg.Store.Path)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		options.Store = s
+	}
+
+	if cfg.Cluster.Token == "" {
+		log.Warn("cluster token is not set, any client can register the agent and receive jobs with credentials of targets")
 	}
 
 	r := router.New(options)
@@ -147,6 +697,95 @@ func runServer(c *cli.Context) error {
 	return r.Serve()
 }
 
+func runAgent(c *cli.Context) error {
+	ctx, cancel := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fmt.Println("setup configuration...")
+
+	cfg := initConfig(c.String(configFlagName))
+
+	fmt.Printf("===============\n%+v\n===============\n", cfg.Redacted())
+
+	fmt.Println("setup configuration done.")
+
+	log := logger.New(ctx, cfg.LogLevel, os.Stdout)
+
+	coordinator := c.String(coordinatorFlagName)
+	if coordinator == "" {
+		coordinator = cfg.Cluster.Coordinator
+	}
+
+	name := c.String(agentNameFlagName)
+	if name == "" {
+		name = cfg.Cluster.AgentName
+	}
+
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+
+	token := c.String(agentTokenFlagName)
+	if token == "" {
+		token = cfg.Cluster.Token
+	}
+
+	tracer := tracing.New(cfg.Tracing, log)
+	defer tracer.Shutdown()
+
+	agent := cluster.NewAgent(coordinator, name, token, log)
+
+	agent.SetTracer(tracer)
+
+	if cfg.SelfMonitor.Enabled {
+		agent.EnableSelfMonitor(selfmon.FromConfig(cfg.SelfMonitor))
+	}
+
+	fmt.Printf("agent %s is connecting to the coordinator %s ...\n", name, coordinator)
+
+	return agent.Run(ctx)
+}
+
+// saveRun saves the run to the runs history if it is enabled
+func saveRun(storeCfg config.Store, run *store.Run) error {
+	if storeCfg.Path == "" {
+		return nil
+	}
+
+	s, err := store.Open(storeCfg.Path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	err = s.Save(run)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("run saved with id %d.\n", run.ID)
+
+	return nil
+}
+
+func itemsKeys(items []url_item.Item) []tester.Key {
+	result := make([]tester.Key, 0, len(items))
+	for _, item := range items {
+		result = append(result, tester.Key{Host: item.Host, URL: item.Url})
+	}
+
+	return result
+}
+
+func reportByURL(report map[tester.Key]tester.Item) map[string]tester.Item {
+	result := make(map[string]tester.Item, len(report))
+	for key, item := range report {
+		result[key.URL] = item
+	}
+
+	return result
+}
+
 func initConfig(configFile string) config.Config {
 	v := viper.New()
 	v.SetConfigName("config")
@@ -209,13 +848,22 @@ func loadTestItems(csvFile, url string) ([]url_item.Item, error) {
 			return nil, err
 		}
 
-		result = append(result, url_item.Item{
+		item := url_item.Item{
 			Host: parsedURL.Hostname(),
 			Url:  parsedURL.String(),
-		})
+		}
+
+		if len(record) > 1 && record[1] != "" {
+			item.Weight, err = strconv.ParseFloat(record[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight of %s: %w", record[0], err)
+			}
+		}
+
+		result = append(result, item)
 	}
 
-	return result, nil
+	return result, tester.ValidateWeights(result)
 }
 
 const (
@@ -223,17 +871,189 @@ const (
 	reportSplitRow       = "======================================="
 )
 
-func formattedOutputReport(report map[tester.Key]tester.Item) {
+func formattedOutputReport(report map[string]tester.Item) {
 	fmt.Println(reportSplitRow)
 
-	for key, item := range report {
-		fmt.Printf("Load test for %s.\n", key.URL)
+	for name, item := range report {
+		fmt.Printf("Load test for %s.\n", name)
+		fmt.Printf("Measured window %s - %s, excluded warm-up requests %d, cool-down requests %d "+
+			"with failed %d and slow %d.\n",
+			item.MeasuredFrom.Format(time.RFC3339), item.MeasuredTo.Format(time.RFC3339),
+			item.WarmUpReqCount, item.CoolDownReqCount, item.CoolDownErrCount, item.CoolDownSlowCount)
 		fmt.Printf("Total sends requests %d.\n", item.TotalReqCount)
 		fmt.Printf("Failed requests %d.\n", item.ErrRequestCount)
 		fmt.Printf("Slow requests %d.\n", item.SlowReqCount)
+		fmt.Printf("Rate limited responses %d, retries %d.\n", item.RateLimitedCount, item.RetryCount)
+
+		for addr, addrItem := range item.Addrs {
+			fmt.Printf("Address %s: sends requests %d, failed %d, request time p50 %.3f s, p99 %.3f s.\n",
+				addr, addrItem.TotalReqCount, addrItem.ErrRequestCount,
+				addrItem.Latency.Quantile(0.5).Seconds(), addrItem.Latency.Quantile(0.99).Seconds())
+		}
+
+		if item.AuthErrCount > 0 {
+			fmt.Printf("Auth errors %d, requests are not sent.\n", item.AuthErrCount)
+		}
+
+		fmt.Printf("Connections: requests on new %d, reused %d, opened %d, errors %d, peak open of the host %d.\n",
+			item.NewConnCount, item.ReusedConnCount, item.ConnOpenedCount, item.ConnErrCount, item.PeakOpenConns)
+
+		if item.ConnIdle != nil {
+			fmt.Printf("Idle time of reused connections p50 %.3f s, p99 %.3f s.\n",
+				item.ConnIdle.Quantile(0.5).Seconds(), item.ConnIdle.Quantile(0.99).Seconds())
+		}
+
+		if item.RedirectCount > 0 || item.RedirectBlockedCount > 0 || item.RedirectErrCount > 0 {
+			fmt.Printf("Redirects %d of requests %d, max hops %d, not followed %d, too many redirects %d, "+
+				"status codes %v.\n", item.RedirectCount, item.RedirectedReqCount, item.MaxRedirectHops,
+				item.RedirectBlockedCount, item.RedirectErrCount, item.RedirectStatusCodes)
+		}
+
+		for hop := 1; hop <= len(item.RedirectHops); hop++ {
+			h, ok := item.RedirectHops[strconv.Itoa(hop)]
+			if !ok {
+				continue
+			}
+
+			fmt.Printf("Redirect hop %d: count %d, time p50 %.3f s, p99 %.3f s.\n",
+				hop, h.Count, h.Quantile(0.5).Seconds(), h.Quantile(0.99).Seconds())
+		}
+
+		for proto, count := range item.Protocols {
+			fmt.Printf("Protocol %s: responses %d, new connections %d.\n", proto, count, item.ProtocolConns[proto])
+		}
+
+		for addr, addrItem := range item.Sources {
+			fmt.Printf("Local address %s: sends requests %d, failed %d, request time p50 %.3f s, p99 %.3f s.\n",
+				addr, addrItem.TotalReqCount, addrItem.ErrRequestCount,
+				addrItem.Latency.Quantile(0.5).Seconds(), addrItem.Latency.Quantile(0.99).Seconds())
+		}
+
+		if item.TLSHandshake != nil {
+			fmt.Printf("TLS handshakes %d, resumed %d, time p50 %.3f s, p99 %.3f s, versions %v, cipher suites %v.\n",
+				item.TLSHandshake.Count, item.TLSResumedCount, item.TLSHandshake.Quantile(0.5).Seconds(),
+				item.TLSHandshake.Quantile(0.99).Seconds(), item.TLSVersions, item.TLSCipherSuites)
+		}
+
+		fmt.Printf("Payload: sent %d bytes, %.0f bytes/s, received %d bytes, %.0f bytes/s, mean response size %.0f bytes.\n",
+			item.ReqBytes, item.SendRate(), item.RespBytes, item.ReceiveRate(), item.MeanRespSize())
+
+		if item.CompressedRespCount > 0 {
+			fmt.Printf("Compressed responses %d, bodies transferred %d bytes, decompressed %d bytes.\n",
+				item.CompressedRespCount, item.RespBodyBytes, item.RespDecodedBytes)
+		}
+
+		for _, phase := range []string{tester.PhaseDNS, tester.PhaseConnect, tester.PhaseProxyConnect,
+			tester.PhaseSend, tester.PhaseWait, tester.PhaseReceive} {
+			h, ok := item.Phases[phase]
+			if !ok {
+				continue
+			}
+
+			fmt.Printf("Phase %s: count %d, time p50 %.3f s, p99 %.3f s.\n",
+				phase, h.Count, h.Quantile(0.5).Seconds(), h.Quantile(0.99).Seconds())
+		}
 		fmt.Printf("Max request time %v s.\n", item.MaxReqTime)
+		fmt.Printf("Request time p50 %.3f s, p95 %.3f s, p99 %.3f s.\n",
+			item.Percentile(50), item.Percentile(95), item.Percentile(99))
+		if item.LatencyCorrected {
+			fmt.Printf("Corrected request time p50 %.3f s, p95 %.3f s, p99 %.3f s.\n",
+				item.CorrectedPercentile(50), item.CorrectedPercentile(95), item.CorrectedPercentile(99))
+		} else {
+			fmt.Println("Corrected request time is inactive without the rounds schedule of LoadTest.RoundInterval.")
+		}
+
+		if item.LateRounds > 0 {
+			fmt.Printf("WARNING: load generator couldn't keep up with the intended schedule, "+
+				"late rounds %d, max schedule lag %.3f s.\n", item.LateRounds, item.MaxScheduleLag)
+		}
+
 		fmt.Println(reportSplitResultRow)
 		fmt.Printf("Recommended requests count %d\n", item.RecommendReqCount)
 		fmt.Println(reportSplitRow)
 	}
 }
+
+func formattedOutputGenerator(report *selfmon.Report) {
+	if report == nil {
+		return
+	}
+
+	fmt.Println("Load generator resources usage.")
+	fmt.Printf("CPU usage avg %.1f%%, max %.1f%%.\n", report.CPUUsageAvg, report.CPUUsageMax)
+	fmt.Printf("Goroutines max %d.\n", report.GoroutinesMax)
+	fmt.Printf("GC count %d, pause total %.3f s, max %.3f s.\n", report.GCCount, report.GCPauseTotal, report.GCPauseMax)
+	fmt.Printf("Open files max %d of limit %d.\n", report.OpenFDsMax, report.FDLimit)
+	fmt.Printf("Scheduling lag max %.3f s.\n", report.SchedLagMax)
+
+	if report.Saturated {
+		fmt.Println(reportSplitRow)
+		fmt.Println("WARNING: LOAD GENERATOR IS SATURATED, IT IS THE BOTTLENECK INSTEAD OF THE TARGET, RESULTS ARE NOT RELIABLE.")
+
+		for _, warning := range report.Warnings {
+			fmt.Printf("- %s\n", warning)
+		}
+	}
+
+	fmt.Println(reportSplitRow)
+}
+
+func formattedOutputMix(mix map[string]tester.MixItem, all map[string]tester.Item) {
+	fmt.Println("Weighted mix.")
+
+	for url, item := range mix {
+		fmt.Printf("%s intended share %.1f%%, measured share %.1f%%.\n", url, item.Weight*100, item.Share*100)
+	}
+
+	for _, item := range all {
+		fmt.Printf("Mix total sends requests %d, failed %d, slow %d, recommended requests count %d.\n",
+			item.TotalReqCount, item.ErrRequestCount, item.SlowReqCount, item.RecommendReqCount)
+		fmt.Printf("Mix request time p50 %.3f s, p95 %.3f s, p99 %.3f s.\n",
+			item.Percentile(50), item.Percentile(95), item.Percentile(99))
+	}
+
+	fmt.Println(reportSplitRow)
+}
+
+func formattedOutputVerdicts(verdicts map[string]tester.BudgetVerdict) {
+	if len(verdicts) == 0 {
+		return
+	}
+
+	for budget, verdict := range verdicts {
+		if verdict.Saturated {
+			fmt.Printf("Capacity of %s (%d urls) is %d concurrent requests, %s failed under the next round.\n",
+				budget, verdict.URLs, verdict.MaxConcurrency, verdict.FailedURL)
+
+			continue
+		}
+
+		fmt.Printf("Capacity of %s (%d urls) is at least %d concurrent requests, it is not saturated.\n",
+			budget, verdict.URLs, verdict.MaxConcurrency)
+	}
+
+	fmt.Println(reportSplitRow)
+}
+
+func formattedOutputTrials(summaries map[string]tester.TrialsSummary) {
+	fmt.Println(reportSplitRow)
+
+	estimate := func(name string, e tester.Estimate) {
+		fmt.Printf("%s mean %.3f, 95%% CI [%.3f, %.3f], CV %.1f%%.\n", name, e.Mean, e.CILow, e.CIHigh, e.CV*100)
+	}
+
+	for name, summary := range summaries {
+		fmt.Printf("Trials summary for %s, trials %d.\n", name, summary.Trials)
+		estimate("Recommended requests count", summary.RecommendReqCount)
+		estimate("Throughput rps", summary.Throughput)
+		estimate("Request time p50 s", summary.P50)
+		estimate("Request time p95 s", summary.P95)
+		estimate("Request time p99 s", summary.P99)
+
+		if len(summary.Unstable) > 0 {
+			fmt.Printf("WARNING: results vary beyond the threshold: %s.\n", strings.Join(summary.Unstable, ", "))
+		}
+
+		fmt.Println(reportSplitRow)
+	}
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// Types of authentication
+const (
+	TypeBasic  = "basic"
+	TypeBearer = "bearer"
+	TypeOAuth2 = "oauth2" // client credentials grant
+	TypeJWT    = "jwt"
+	TypeHMAC   = "hmac"
+	TypeSigV4  = "sigv4"
+)
+
+const (
+	authorizationHeader = "Authorization"
+
+	tokenTimeout = 15 * time.Second
+
+	// redacted replaces secrets of responses, runs history and logs like url.URL.Redacted
+	redacted = "xxxxx"
+)
+
+var (
+	// ErrAuth is the error of the authentication of the request: token fetch and signing errors,
+	// the request is not sent
+	ErrAuth = errors.New("authentication failed")
+
+	errInvalidAuth = errors.New("invalid auth")
+)
+
+// Options represents the authentication of target requests, requests are sent without it if Type is empty
+type Options struct {
+	Type string `json:"type,omitempty"` // basic, bearer, oauth2, jwt, hmac, sigv4
+
+	// basic
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// bearer static token
+	Token string `json:"token,omitempty"`
+
+	// oauth2 client credentials, the token is shared by workers and refreshed before its expiration
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	// jwt minted locally, iat, exp and jti claims are added, the token is reused for a half of TTL
+	Algorithm string                 `json:"algorithm,omitempty"` // HS256, RS256, ES256
+	KeyFile   string                 `json:"key_file,omitempty"`  // pem private key of RS256 and ES256
+	Claims    map[string]interface{} `json:"claims,omitempty"`
+	TTL       time.Duration          `json:"ttl,omitempty"` // lifetime of the jwt, 1 minute if 0
+
+	// the secret of HS256 and hmac
+	Secret string `json:"secret,omitempty"`
+	// hmac signature header, X-Signature if empty
+	Header string `json:"header,omitempty"`
+
+	// aws sigv4
+	AccessKey    string `json:"access_key,omitempty"`
+	SecretKey    string `json:"secret_key,omitempty"`
+	SessionToken string `json:"session_token,omitempty"`
+	Region       string `json:"region,omitempty"`
+	Service      string `json:"service,omitempty"`
+}
+
+func FromConfig(cfg config.Auth) Options {
+	return Options{
+		Type:         cfg.Type,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		Token:        cfg.Token,
+		TokenURL:     cfg.TokenURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		Scopes:       cfg.Scopes,
+		Algorithm:    cfg.Algorithm,
+		KeyFile:      cfg.KeyFile,
+		Claims:       cfg.Claims,
+		TTL:          time.Duration(cfg.TTL) * time.Second,
+		Secret:       cfg.Secret,
+		Header:       cfg.Header,
+		AccessKey:    cfg.AccessKey,
+		SecretKey:    cfg.SecretKey,
+		SessionToken: cfg.SessionToken,
+		Region:       cfg.Region,
+		Service:      cfg.Service,
+	}
+}
+
+// Override returns the authentication of the url if it is set
+func (o Options) Override(item *Options) Options {
+	if item == nil || item.Type == "" {
+		return o
+	}
+
+	return *item
+}
+
+// Redacted returns options with secrets replaced by "xxxxx", they are used in responses, runs history and logs
+func (o Options) Redacted() Options {
+	for _, secret := range []*string{&o.Password, &o.Token, &o.ClientSecret, &o.Secret, &o.SecretKey, &o.SessionToken} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+
+	return o
+}
+
+// Validate checks required options of the type and keys
+func (o Options) Validate() error {
+	_, err := New(o)
+
+	return err
+}
+
+// Provider authenticates requests
+type Provider interface {
+	Authenticate(req *http.Request) error
+}
+
+// New returns the provider of options, nil if Type is empty
+func New(o Options) (Provider, error) {
+	required := func(values ...string) error {
+		for _, v := range values {
+			if v == "" {
+				return fmt.Errorf("%w: required options of %s are not set", errInvalidAuth, o.Type)
+			}
+		}
+
+		return nil
+	}
+
+	switch o.Type {
+	case "":
+		return nil, nil
+	case TypeBasic:
+		return basic{username: o.Username, password: o.Password}, required(o.Username)
+	case TypeBearer:
+		return bearer{token: o.Token}, required(o.Token)
+	case TypeOAuth2:
+		if err := required(o.TokenURL, o.ClientID); err != nil {
+			return nil, err
+		}
+
+		return newOAuth2(o), nil
+	case TypeJWT:
+		return newJWT(o)
+	case TypeHMAC:
+		if err := required(o.Secret); err != nil {
+			return nil, err
+		}
+
+		return newHMAC(o), nil
+	case TypeSigV4:
+		if err := required(o.AccessKey, o.SecretKey, o.Region, o.Service); err != nil {
+			return nil, err
+		}
+
+		return sigV4{opts: o}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q, expected one of: basic, bearer, oauth2, jwt, hmac, sigv4",
+			errInvalidAuth, o.Type)
+	}
+}
+
+type basic struct {
+	username string
+	password string
+}
+
+func (b basic) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(b.username, b.password)
+
+	return nil
+}
+
+type bearer struct {
+	token string
+}
+
+func (b bearer) Authenticate(req *http.Request) error {
+	req.Header.Set(authorizationHeader, "Bearer "+b.token)
+
+	return nil
+}
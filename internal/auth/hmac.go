@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSignatureHeader = "X-Signature"
+	timestampHeader        = "X-Timestamp"
+)
+
+// hmacSigner signs requests by HMAC-SHA256 of the method, the path with the query, the unix timestamp of
+// the X-Timestamp header and the sha256 of the body, lines are joined by "\n" and the signature is hex
+type hmacSigner struct {
+	secret []byte
+	header string
+}
+
+func newHMAC(opts Options) hmacSigner {
+	header := opts.Header
+	if header == "" {
+		header = defaultSignatureHeader
+	}
+
+	return hmacSigner{secret: []byte(opts.Secret), header: header}
+}
+
+func (h hmacSigner) Authenticate(req *http.Request) error {
+	bodyHash, err := hashBody(req)
+	if err != nil {
+		return fmt.Errorf("%w: hmac: %s", ErrAuth, err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set(timestampHeader, timestamp)
+	req.Header.Set(h.header, h.sign(req.Method, req.URL.RequestURI(), timestamp, bodyHash))
+
+	return nil
+}
+
+// sign returns the hex signature of the request
+func (h hmacSigner) sign(method, requestURI, timestamp, bodyHash string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + bodyHash))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashBody returns the hex sha256 of the request body, the body is read from its copy
+func hashBody(req *http.Request) (string, error) {
+	hash := sha256.New()
+
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		defer body.Close()
+
+		if _, err = io.Copy(hash, body); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// TestHMACSign checks the known signature and headers of the signed request
+func TestHMACSign(t *testing.T) {
+	h := newHMAC(Options{Type: TypeHMAC, Secret: "secret"})
+
+	got := h.sign(http.MethodPost, "/api/items?id=1", "1700000000",
+		"015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862")
+	if expected := "9be4ae347c3e91f543ba7428acc8a574aae28c4e0a8d16d4f271d338f4ae1db6"; got != expected {
+		t.Fatalf("signature %s, expected %s", got, expected)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "https://test.com/api/items?id=1", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = h.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timestamp := req.Header.Get(timestampHeader)
+
+	expected := h.sign(http.MethodPost, "/api/items?id=1", timestamp,
+		"015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862")
+	if got := req.Header.Get(defaultSignatureHeader); timestamp == "" || got != expected {
+		t.Fatalf("signature %s of timestamp %q, expected %s", got, timestamp, expected)
+	}
+}
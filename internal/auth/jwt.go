@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Algorithms of jwt
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+const defaultJWTTTL = time.Minute
+
+// jwt mints tokens signed by the key, the token is reused by workers for a half of its lifetime
+type jwt struct {
+	opts Options
+	sign func(data []byte) ([]byte, error)
+
+	mx        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+func newJWT(opts Options) (*jwt, error) {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultJWTTTL
+	}
+
+	j := &jwt{opts: opts}
+
+	switch opts.Algorithm {
+	case AlgorithmHS256:
+		if opts.Secret == "" {
+			return nil, fmt.Errorf("%w: secret of HS256 is not set", errInvalidAuth)
+		}
+
+		j.sign = func(data []byte) ([]byte, error) {
+			mac := hmac.New(sha256.New, []byte(opts.Secret))
+			mac.Write(data)
+
+			return mac.Sum(nil), nil
+		}
+	case AlgorithmRS256, AlgorithmES256:
+		key, err := loadKey(opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		j.sign, err = signer(opts.Algorithm, key)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown jwt algorithm %q, expected HS256, RS256 or ES256",
+			errInvalidAuth, opts.Algorithm)
+	}
+
+	return j, nil
+}
+
+func (j *jwt) Authenticate(req *http.Request) error {
+	token, err := j.get()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(authorizationHeader, "Bearer "+token)
+
+	return nil
+}
+
+func (j *jwt) get() (string, error) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	now := time.Now()
+
+	if j.token != "" && now.Before(j.refreshAt) {
+		return j.token, nil
+	}
+
+	token, err := j.mint(now)
+	if err != nil {
+		return "", fmt.Errorf("%w: mint jwt: %s", ErrAuth, err)
+	}
+
+	j.token, j.refreshAt = token, now.Add(j.opts.TTL/2)
+
+	return j.token, nil
+}
+
+// mint returns the new token with claims of options and iat, exp and jti
+func (j *jwt) mint(now time.Time) (string, error) {
+	claims := make(map[string]interface{}, len(j.opts.Claims)+3)
+	for k, v := range j.opts.Claims {
+		claims[k] = v
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(j.opts.TTL).Unix()
+	claims["jti"] = hex.EncodeToString(id)
+
+	header, err := jsoniter.Marshal(map[string]string{"alg": j.opts.Algorithm, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := jsoniter.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signature, err := j.sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// loadKey loads the pem private key: PKCS#8, PKCS#1 or SEC 1
+func loadKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", errInvalidAuth, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no pem key in %s", errInvalidAuth, path)
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown private key in %s", errInvalidAuth, path)
+}
+
+// signer returns the sign func of the algorithm, signatures of ES256 are r and s of 32 bytes
+func signer(algorithm string, key crypto.Signer) (func(data []byte) ([]byte, error), error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != AlgorithmRS256 {
+			break
+		}
+
+		return func(data []byte) ([]byte, error) {
+			digest := sha256.Sum256(data)
+
+			return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		}, nil
+	case *ecdsa.PrivateKey:
+		if algorithm != AlgorithmES256 || k.Curve.Params().BitSize != 256 {
+			break
+		}
+
+		return func(data []byte) ([]byte, error) {
+			digest := sha256.Sum256(data)
+
+			r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+			if err != nil {
+				return nil, err
+			}
+
+			signature := make([]byte, 64)
+			fillBytes(r, signature[:32])
+			fillBytes(s, signature[32:])
+
+			return signature, nil
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: the key doesn't match the algorithm %s", errInvalidAuth, algorithm)
+}
+
+// fillBytes sets the absolute value of x as the zero-extended big-endian byte slice buf
+func fillBytes(x *big.Int, buf []byte) {
+	b := x.Bytes()
+	copy(buf[len(buf)-len(b):], b)
+}
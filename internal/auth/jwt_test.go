@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+// TestJWTSignHS256 checks the HS256 signature of the example of RFC 7515 appendix A.1
+func TestJWTSignHS256(t *testing.T) {
+	key, err := base64.RawURLEncoding.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := newJWT(Options{Type: TypeJWT, Algorithm: AlgorithmHS256, Secret: string(key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signingInput := "eyJ0eXAiOiJKV1QiLA0KICJhbGciOiJIUzI1NiJ9." +
+		"eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ"
+
+	signature, err := j.sign([]byte(signingInput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := base64.RawURLEncoding.EncodeToString(signature), "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"; got != expected {
+		t.Fatalf("signature %s, expected %s", got, expected)
+	}
+}
+
+// TestJWTMint checks the header, claims and the signature of the minted token
+func TestJWTMint(t *testing.T) {
+	j, err := newJWT(Options{Type: TypeJWT, Algorithm: AlgorithmHS256, Secret: "secret",
+		Claims: map[string]interface{}{"sub": "load"}, TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+
+	token, err := j.mint(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %s", token)
+	}
+
+	signature, err := j.sign([]byte(parts[0] + "." + parts[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parts[2] != base64.RawURLEncoding.EncodeToString(signature) {
+		t.Fatalf("signature %s doesn't match the token", parts[2])
+	}
+
+	var header map[string]string
+	var claims map[string]interface{}
+
+	for i, v := range []interface{}{&header, &claims} {
+		data, err := base64.RawURLEncoding.DecodeString(parts[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = jsoniter.Unmarshal(data, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if header["alg"] != AlgorithmHS256 || header["typ"] != "JWT" {
+		t.Fatalf("header %v", header)
+	}
+
+	if claims["sub"] != "load" || claims["iat"] != float64(now.Unix()) || claims["exp"] != float64(now.Unix()+60) || claims["jti"] == "" {
+		t.Fatalf("claims %v", claims)
+	}
+}
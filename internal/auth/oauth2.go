@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// the token is refreshed before its expiration by this part of its lifetime
+	refreshBefore = 10
+
+	// failed fetches are not repeated during this delay, so requests of workers don't flood the token endpoint
+	fetchRetryDelay = time.Second
+
+	defaultTokenLifetime = time.Hour
+)
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"` // sec
+}
+
+// oauth2 fetches the token by the client credentials grant, the token is shared by workers of the load test
+type oauth2 struct {
+	opts   Options
+	client *http.Client
+
+	mx        sync.Mutex
+	token     string
+	refreshAt time.Time
+	err       error
+	failedAt  time.Time
+}
+
+func newOAuth2(opts Options) *oauth2 {
+	return &oauth2{
+		opts:   opts,
+		client: &http.Client{Timeout: tokenTimeout},
+	}
+}
+
+func (o *oauth2) Authenticate(req *http.Request) error {
+	token, err := o.get(req.Context())
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(authorizationHeader, "Bearer "+token)
+
+	return nil
+}
+
+// get returns the valid token, only one worker fetches the token while others wait for it,
+// the fetch isn't canceled with the request of the worker, because the token is shared by all urls of the provider
+func (o *oauth2) get(ctx context.Context) (string, error) {
+	o.mx.Lock()
+	defer o.mx.Unlock()
+
+	now := time.Now()
+
+	if o.token != "" && now.Before(o.refreshAt) {
+		return o.token, nil
+	}
+
+	if o.err != nil && now.Sub(o.failedAt) < fetchRetryDelay {
+		return "", o.err
+	}
+
+	// the stopped worker doesn't fetch the token, its cancellation isn't the failed fetch
+	err := ctx.Err()
+	if err != nil {
+		return "", err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(context.Background(), tokenTimeout)
+	defer cancel()
+
+	token, lifetime, err := o.fetch(fetchCtx)
+	if err != nil {
+		o.err, o.failedAt = fmt.Errorf("%w: fetch token: %s", ErrAuth, err), now
+
+		return "", o.err
+	}
+
+	o.token, o.err = token, nil
+	o.refreshAt = now.Add(lifetime - lifetime/refreshBefore)
+
+	return o.token, nil
+}
+
+func (o *oauth2) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(o.opts.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.opts.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.opts.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(o.opts.ClientID), url.QueryEscape(o.opts.ClientSecret))
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint responded %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+
+	err = jsoniter.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("token endpoint responded without access_token")
+	}
+
+	lifetime := time.Duration(token.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+
+	return token.AccessToken, lifetime, nil
+}
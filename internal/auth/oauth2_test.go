@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestOAuth2CanceledRequest checks that the canceled request of the stopped url doesn't fail the token
+// of other urls of the provider
+func TestOAuth2CanceledRequest(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token1","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	o := newOAuth2(Options{Type: TypeOAuth2, TokenURL: tokenServer.URL, ClientID: "id", ClientSecret: "secret"})
+
+	stopped, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := o.get(stopped)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrAuth) {
+		t.Fatalf("canceled request: error %v, expected %v", err, context.Canceled)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://test.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = o.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := req.Header.Get(authorizationHeader); got != "Bearer token1" {
+		t.Fatalf("authorization %q, expected the bearer token", got)
+	}
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	sigV4Date      = "20060102T150405Z"
+
+	amzDateHeader          = "X-Amz-Date"
+	amzContentSHA256Header = "X-Amz-Content-Sha256"
+	amzSecurityTokenHeader = "X-Amz-Security-Token"
+
+	serviceS3 = "s3"
+)
+
+// sigV4 signs requests by AWS Signature Version 4
+type sigV4 struct {
+	opts Options
+}
+
+func (s sigV4) Authenticate(req *http.Request) error {
+	bodyHash, err := hashBody(req)
+	if err != nil {
+		return fmt.Errorf("%w: sigv4: %s", ErrAuth, err)
+	}
+
+	amzDate := time.Now().UTC().Format(sigV4Date)
+
+	req.Header.Set(amzDateHeader, amzDate)
+	req.Header.Set(amzContentSHA256Header, bodyHash)
+
+	if s.opts.SessionToken != "" {
+		req.Header.Set(amzSecurityTokenHeader, s.opts.SessionToken)
+	}
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": bodyHash,
+		"x-amz-date":           amzDate,
+	}
+
+	if s.opts.SessionToken != "" {
+		headers["x-amz-security-token"] = s.opts.SessionToken
+	}
+
+	req.Header.Set(authorizationHeader, s.sign(req.Method, req.URL, headers, bodyHash, amzDate))
+
+	return nil
+}
+
+// sign returns the authorization header of the request with canonical headers, amzDate is the time of the signature
+func (s sigV4) sign(method string, u *url.URL, headers map[string]string, bodyHash, amzDate string) string {
+	date := amzDate[:8]
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI(u, s.opts.Service),
+		canonicalQuery(u.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		bodyHash,
+	}, "\n")
+
+	scope := date + "/" + s.opts.Region + "/" + s.opts.Service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := sigV4Algorithm + "\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, s.opts.Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.opts.AccessKey, scope, signedHeaders, signature)
+}
+
+// canonicalURI returns the path with every segment of the escaped path encoded again,
+// services other than S3 expect the path encoded twice, S3 expects the escaped path
+func canonicalURI(u *url.URL, service string) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	if service == serviceS3 {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = escape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query sorted by names and values, spaces are encoded as %20
+func canonicalQuery(query url.Values) string {
+	params := make([]string, 0, len(query))
+
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, escape(name)+"="+escape(value))
+		}
+	}
+
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
+
+// escape encodes all characters except unreserved ones A-Z, a-z, 0-9, "-", ".", "_" and "~"
+func escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
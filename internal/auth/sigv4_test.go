@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// TestSigV4Sign checks signatures of requests of the AWS Signature Version 4 test suite,
+// the path with the space is encoded twice like paths of services other than S3
+func TestSigV4Sign(t *testing.T) {
+	s := sigV4{opts: Options{
+		Type:      TypeSigV4,
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:    "us-east-1",
+		Service:   "service",
+	}}
+
+	emptyHash := sha256.Sum256(nil)
+
+	cases := []struct {
+		name      string
+		method    string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", http.MethodGet, "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", http.MethodGet, "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-unreserved", http.MethodGet, "https://example.amazonaws.com/-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", "07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f"},
+		{"post-vanilla", http.MethodPost, "https://example.amazonaws.com/", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"path encoded twice", http.MethodGet, "https://example.amazonaws.com/example%20space/", "446b817944c553435b35e813c261ff4e161fff982d1bacdef1c87f6785dd1662"},
+	}
+
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers := map[string]string{"host": "example.amazonaws.com", "x-amz-date": "20150830T123600Z"}
+
+		got := s.sign(c.method, u, headers, hex.EncodeToString(emptyHash[:]), "20150830T123600Z")
+		expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=" + c.signature
+
+		if got != expected {
+			t.Errorf("%s: %s, expected %s", c.name, got, expected)
+		}
+	}
+}
+
+// TestCanonicalURI checks that segments of paths are encoded twice, except for S3
+func TestCanonicalURI(t *testing.T) {
+	cases := []struct {
+		url      string
+		service  string
+		expected string
+	}{
+		{"https://test.com", "service", "/"},
+		{"https://test.com/example space/", "service", "/example%2520space/"},
+		{"https://test.com/ሴ", "service", "/%25E1%2588%25B4"},
+		{"https://test.com/a%2Fb/c:d", "service", "/a%252Fb/c%3Ad"},
+		{"https://test.com/example space/", serviceS3, "/example%20space/"},
+	}
+
+	for _, c := range cases {
+		u, err := url.Parse(c.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := canonicalURI(u, c.service); got != c.expected {
+			t.Errorf("%s of %s: %s, expected %s", c.url, c.service, got, c.expected)
+		}
+	}
+}
+
+// TestSigV4Authenticate checks headers of the signed request
+func TestSigV4Authenticate(t *testing.T) {
+	s := sigV4{opts: Options{Type: TypeSigV4, AccessKey: "id", SecretKey: "key", SessionToken: "session",
+		Region: "us-east-1", Service: "execute-api"}}
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.test.com/items", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := req.Header.Get(amzContentSHA256Header); got != "015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862" {
+		t.Fatalf("content hash %s", got)
+	}
+
+	if got := req.Header.Get(amzSecurityTokenHeader); got != "session" {
+		t.Fatalf("security token %q", got)
+	}
+
+	if got := req.Header.Get(authorizationHeader); !strings.Contains(got,
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token, Signature=") {
+		t.Fatalf("authorization %s", got)
+	}
+}
@@ -36,11 +36,15 @@ type Cache struct {
 
 	stopCh  chan struct{}
 	stopped uint32
+
+	hits   uint64
+	misses uint64
 }
 
 func New() *Cache {
 	c := &Cache{
-		m: sync.Map{},
+		m:      sync.Map{},
+		stopCh: make(chan struct{}),
 	}
 
 	go c.clean()
@@ -51,22 +55,49 @@ func New() *Cache {
 func (c *Cache) Get(confHash string, key tester.Key) (Item, bool) {
 	i, ok := c.m.Load(key)
 	if !ok {
+		atomic.AddUint64(&c.misses, 1)
 		return Item{}, false
 	}
 
 	item, ok := i.(Item)
 	if !ok {
+		atomic.AddUint64(&c.misses, 1)
 		return Item{}, false
 	}
 
 	if item.confHash != confHash {
 		c.m.Delete(key)
+		atomic.AddUint64(&c.misses, 1)
 		return Item{}, false
 	}
 
+	atomic.AddUint64(&c.hits, 1)
+
 	return item, true
 }
 
+// Len returns the number of items in the cache
+func (c *Cache) Len() int {
+	var count int
+
+	c.m.Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+
+	return count
+}
+
+// Hits returns the number of found items
+func (c *Cache) Hits() uint64 {
+	return atomic.LoadUint64(&c.hits)
+}
+
+// Misses returns the number of not found items
+func (c *Cache) Misses() uint64 {
+	return atomic.LoadUint64(&c.misses)
+}
+
 func (c *Cache) Set(confHash string, key tester.Key, i tester.Item, expiration time.Duration) {
 	c.m.Store(key, newItem(confHash, i, expiration))
 }
@@ -95,7 +126,7 @@ func (c *Cache) clean() {
 					return true
 				}
 
-				if item.exp.After(time.Now()) {
+				if time.Now().After(item.exp) {
 					c.m.Delete(key)
 					return true
 				}
@@ -0,0 +1,279 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/selfmon"
+	"github.com/tagirmukail/ldtester/internal/tester"
+	"github.com/tagirmukail/ldtester/internal/tracing"
+)
+
+const (
+	reportInterval = time.Second
+	retryInterval  = 3 * time.Second
+
+	agentHTTPClientTimeout = pollTimeout + 10*time.Second
+)
+
+// TokenHeader is the header of the shared token of the coordinator and agents
+const TokenHeader = "X-Ldtester-Token"
+
+var errUnexpectedStatus = errors.New("unexpected status code")
+
+// Agent runs shares of distributed load tests received from the coordinator
+type Agent struct {
+	coordinator string
+	name        string
+	token       string
+
+	log logrus.FieldLogger
+	cli *http.Client
+
+	tracer *tracing.Tracer
+
+	monitorOpts *selfmon.Options
+
+	id uint64
+}
+
+// NewAgent returns the agent of the coordinator with address http://host:port, token is the shared token of
+// the coordinator and agents, it is sent if it is set
+func NewAgent(coordinator, name, token string, log logrus.FieldLogger) *Agent {
+	return &Agent{
+		coordinator: strings.TrimRight(coordinator, "/"),
+		name:        name,
+		token:       token,
+		log:         log,
+		cli:         &http.Client{Timeout: agentHTTPClientTimeout},
+	}
+}
+
+// SetTracer sets the tracer of load test requests, it must be called before Run
+func (a *Agent) SetTracer(tracer *tracing.Tracer) {
+	a.tracer = tracer
+}
+
+// EnableSelfMonitor enables the monitor of the agent resources usage in jobs, it must be called before Run
+func (a *Agent) EnableSelfMonitor(opts selfmon.Options) {
+	a.monitorOpts = &opts
+}
+
+// Run registers the agent and runs received jobs until ctx is done
+func (a *Agent) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if a.id == 0 {
+			err := a.register(ctx)
+			if err != nil {
+				a.log.WithError(err).Error("agent registration failed")
+				a.wait(ctx, retryInterval)
+
+				continue
+			}
+		}
+
+		job, err := a.nextJob(ctx)
+		switch {
+		case errors.Is(err, ErrUnknownAgent):
+			// the coordinator is restarted or removed the agent
+			a.id = 0
+			continue
+		case err != nil && ctx.Err() != nil:
+			return nil
+		case err != nil:
+			a.log.WithError(err).Error("get job failed")
+			a.wait(ctx, retryInterval)
+
+			continue
+		case job == nil:
+			continue
+		}
+
+		a.runJob(ctx, job)
+	}
+}
+
+func (a *Agent) register(ctx context.Context) error {
+	resp := &RegisterResponse{}
+
+	err := a.do(ctx, http.MethodPost, "/cluster/agents", &RegisterRequest{Name: a.name}, resp)
+	if err != nil {
+		return err
+	}
+
+	a.id = resp.ID
+
+	a.log.WithField("agent_id", a.id).WithField("coordinator", a.coordinator).Info("agent registered")
+
+	return nil
+}
+
+// nextJob waits for the job from the coordinator, returns nil job if there is no job
+func (a *Agent) nextJob(ctx context.Context) (*Job, error) {
+	job := &Job{}
+
+	err := a.do(ctx, http.MethodGet, fmt.Sprintf("/cluster/agents/%d/job", a.id), nil, job)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.ID == 0 {
+		return nil, nil
+	}
+
+	return job, nil
+}
+
+// runJob runs the share of the load test from the start time, reports the current result every second
+func (a *Agent) runJob(ctx context.Context, job *Job) {
+	log := a.log.WithField("job_id", job.ID)
+
+	log.WithField("start_at", job.StartAt).
+		WithField("agent_index", job.AgentIndex).
+		WithField("agents_count", job.AgentsCount).
+		Info("job received")
+
+	a.wait(ctx, time.Until(job.StartAt))
+
+	jobCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	defer cancel()
+
+	conf, items := job.Credentials.apply(job.Config, job.Items)
+
+	t := tester.New(jobCtx, cancel, log, conf, items)
+
+	t.SetShare(job.AgentIndex, job.AgentsCount)
+	t.SetTracer(a.tracer)
+	t.DisableProgressBar()
+
+	if a.monitorOpts != nil {
+		t.SetMonitor(selfmon.New(*a.monitorOpts, log))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		t.Run()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			a.report(ctx, &AgentReport{
+				JobID:     job.ID,
+				Done:      true,
+				Report:    reportByURL(t.Snapshot()),
+				Generator: t.GeneratorReport(),
+				Verdicts:  t.Verdicts(),
+			})
+
+			log.Info("job finished")
+
+			return
+		case <-ticker.C:
+			a.report(ctx, &AgentReport{
+				JobID:     job.ID,
+				Report:    reportByURL(t.Snapshot()),
+				Generator: t.GeneratorReport(),
+			})
+		}
+	}
+}
+
+func (a *Agent) report(ctx context.Context, report *AgentReport) {
+	err := a.do(ctx, http.MethodPost, fmt.Sprintf("/cluster/agents/%d/report", a.id), report, nil)
+	if err != nil {
+		a.log.WithError(err).WithField("job_id", report.JobID).Error("send job report failed")
+	}
+}
+
+// do sends the request to the coordinator and decodes the response data into out
+func (a *Agent) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+
+	if in != nil {
+		err := jsoniter.NewEncoder(&body).Encode(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.coordinator+path, &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if a.token != "" {
+		req.Header.Set(TokenHeader, a.token)
+	}
+
+	resp, err := a.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrUnknownAgent
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("%w: %d", errUnexpectedStatus, resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	envelope := &response{}
+
+	err = jsoniter.NewDecoder(resp.Body).Decode(envelope)
+	if err != nil {
+		return err
+	}
+
+	return jsoniter.Unmarshal(envelope.Data, out)
+}
+
+func (a *Agent) wait(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func reportByURL(report map[tester.Key]tester.Item) map[string]tester.Item {
+	result := make(map[string]tester.Item, len(report))
+	for key, item := range report {
+		result[key.URL] = item
+	}
+
+	return result
+}
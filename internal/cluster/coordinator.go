@@ -0,0 +1,448 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/tester"
+	"github.com/tagirmukail/ldtester/internal/url_item"
+)
+
+const (
+	defaultStartDelay = 2 * time.Second
+
+	pollTimeout = 10 * time.Second
+	agentTTL    = 3 * pollTimeout // agent is not alive if it doesn't poll jobs for this time
+	resultGrace = 30 * time.Second
+
+	finishedJobsLimit = 100 // results of finished jobs are kept for GET /cluster/jobs/{id}, oldest are dropped
+)
+
+var (
+	ErrUnknownAgent    = errors.New("unknown agent")
+	ErrUnknownJob      = errors.New("unknown job")
+	ErrNotEnoughAgents = errors.New("not enough free agents")
+)
+
+type agent struct {
+	id       uint64
+	name     string
+	lastSeen time.Time
+	jobCh    chan *Job
+	busy     bool
+	job      uint64 // id of the current or the last job of the agent
+}
+
+type job struct {
+	id        uint64
+	agents    []uint64
+	results   map[uint64]*AgentResult
+	remaining int
+	done      chan struct{}
+
+	items []url_item.Item // redacted urls of the mix report
+	mix   bool
+}
+
+// Coordinator registers agents, slices the load test between them and merges their reports
+type Coordinator struct {
+	mx sync.Mutex
+
+	log logrus.FieldLogger
+
+	startDelay time.Duration
+
+	agents    map[uint64]*agent
+	jobs      map[uint64]*job
+	finished  map[uint64]JobResult
+	lastAgent uint64
+	lastJob   uint64
+}
+
+// NewCoordinator returns the coordinator, agents start the job after startDelay from the dispatching
+func NewCoordinator(log logrus.FieldLogger, startDelay time.Duration) *Coordinator {
+	if startDelay <= 0 {
+		startDelay = defaultStartDelay
+	}
+
+	return &Coordinator{
+		log:        log,
+		startDelay: startDelay,
+		agents:     make(map[uint64]*agent),
+		jobs:       make(map[uint64]*job),
+		finished:   make(map[uint64]JobResult),
+	}
+}
+
+// Register registers the new agent and returns its id
+func (c *Coordinator) Register(name string) uint64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.lastAgent++
+
+	c.agents[c.lastAgent] = &agent{
+		id:       c.lastAgent,
+		name:     name,
+		lastSeen: time.Now(),
+		jobCh:    make(chan *Job, 1),
+	}
+
+	c.log.WithField("agent_id", c.lastAgent).WithField("name", name).Info("agent registered")
+
+	return c.lastAgent
+}
+
+// NextJob waits for the job of the agent, returns nil job if there is no job for the poll timeout
+func (c *Coordinator) NextJob(ctx context.Context, agentID uint64) (*Job, error) {
+	jobCh, err := c.touch(agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(pollTimeout)
+	defer timer.Stop()
+
+	select {
+	case j := <-jobCh:
+		return j, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	_, err = c.touch(agentID)
+
+	return nil, err
+}
+
+// Requeue returns the job which the agent didn't receive back to the agent, the job is dropped
+// if it is finished or the agent has the newer job
+func (c *Coordinator) Requeue(agentID uint64, job *Job) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	a, ok := c.agents[agentID]
+	if !ok || a.job != job.ID {
+		return
+	}
+
+	if _, ok := c.jobs[job.ID]; !ok {
+		return
+	}
+
+	select {
+	case a.jobCh <- job:
+	default:
+	}
+}
+
+// touch updates the last seen time of the agent
+func (c *Coordinator) touch(agentID uint64) (chan *Job, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	a, ok := c.agents[agentID]
+	if !ok {
+		return nil, ErrUnknownAgent
+	}
+
+	a.lastSeen = time.Now()
+
+	return a.jobCh, nil
+}
+
+// Report saves the current or the final report of the agent job
+func (c *Coordinator) Report(agentID uint64, report *AgentReport) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	a, ok := c.agents[agentID]
+	if !ok {
+		return ErrUnknownAgent
+	}
+
+	a.lastSeen = time.Now()
+
+	j, ok := c.jobs[report.JobID]
+	if !ok {
+		return ErrUnknownJob
+	}
+
+	result, ok := j.results[agentID]
+	if !ok {
+		return ErrUnknownJob
+	}
+
+	if result.Done {
+		return nil
+	}
+
+	result.Report = report.Report
+	result.Generator = report.Generator
+	result.Verdicts = report.Verdicts
+	result.Error = report.Error
+	result.Done = report.Done
+
+	if !report.Done {
+		return nil
+	}
+
+	if a.job == j.id {
+		a.busy = false
+	}
+
+	j.remaining--
+	if j.remaining == 0 {
+		close(j.done)
+	}
+
+	return nil
+}
+
+// Agents returns registered agents, agents which don't poll jobs are removed
+func (c *Coordinator) Agents() []AgentInfo {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.removeDeadAgents()
+
+	result := make([]AgentInfo, 0, len(c.agents))
+	for _, a := range c.agents {
+		result = append(result, AgentInfo{
+			ID:       a.id,
+			Name:     a.name,
+			LastSeen: a.lastSeen,
+			Alive:    a.alive(),
+			Busy:     a.busy,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
+
+// Jobs returns merged current reports of running jobs
+func (c *Coordinator) Jobs() []JobResult {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	result := make([]JobResult, 0, len(c.jobs))
+	for _, j := range c.jobs {
+		result = append(result, j.result())
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
+
+// Start starts the load test on agentsCount free agents, all free agents are used if agentsCount is 0,
+// and returns the id of the job. Every agent sends its share of requests of every round, done receives
+// the merged report of all agents when all agents report their results or after the deadline of the job.
+func (c *Coordinator) Start(conf tester.Configuration, items []url_item.Item, agentsCount int,
+	timeout time.Duration, done func(result JobResult)) (uint64, error) {
+	j, err := c.dispatch(conf, items, agentsCount, timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	go func() {
+		result := c.wait(j, timeout)
+
+		if done != nil {
+			done(result)
+		}
+	}()
+
+	return j.id, nil
+}
+
+// Job returns the merged current report of the running job or the merged report of the finished job
+func (c *Coordinator) Job(id uint64) (JobResult, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if j, ok := c.jobs[id]; ok {
+		return j.result(), nil
+	}
+
+	if result, ok := c.finished[id]; ok {
+		return result, nil
+	}
+
+	return JobResult{}, ErrUnknownJob
+}
+
+// wait waits for results of all agents of the job until its deadline and finishes the job
+func (c *Coordinator) wait(j *job, timeout time.Duration) JobResult {
+	deadline := time.NewTimer(c.startDelay + timeout + resultGrace)
+	defer deadline.Stop()
+
+	select {
+	case <-j.done:
+	case <-deadline.C:
+		c.log.WithField("job_id", j.id).Warn("not all agents reported the job result")
+	}
+
+	return c.finish(j)
+}
+
+// dispatch sends jobs to free agents
+func (c *Coordinator) dispatch(conf tester.Configuration, items []url_item.Item,
+	agentsCount int, timeout time.Duration) (*job, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.removeDeadAgents()
+
+	free := make([]*agent, 0, len(c.agents))
+	for _, a := range c.agents {
+		// the job channel of the free agent is empty, so sending of the job never blocks
+		if !a.busy && len(a.jobCh) == 0 {
+			free = append(free, a)
+		}
+	}
+
+	sort.Slice(free, func(i, j int) bool { return free[i].id < free[j].id })
+
+	if agentsCount == 0 {
+		agentsCount = len(free)
+	}
+
+	if agentsCount == 0 || agentsCount > len(free) {
+		return nil, ErrNotEnoughAgents
+	}
+
+	free = free[:agentsCount]
+
+	c.lastJob++
+
+	j := &job{
+		id:        c.lastJob,
+		results:   make(map[uint64]*AgentResult, agentsCount),
+		remaining: agentsCount,
+		done:      make(chan struct{}),
+		items:     url_item.Redacted(items),
+		mix:       conf.Mix,
+	}
+
+	startAt := time.Now().Add(c.startDelay)
+
+	for i, a := range free {
+		a.busy = true
+		a.job = j.id
+
+		j.agents = append(j.agents, a.id)
+		j.results[a.id] = &AgentResult{AgentID: a.id, Name: a.name}
+
+		a.jobCh <- &Job{
+			ID:          j.id,
+			AgentIndex:  i,
+			AgentsCount: agentsCount,
+			StartAt:     startAt,
+			Timeout:     timeout,
+			Config:      conf.Redacted(),
+			Items:       url_item.Redacted(items),
+			Credentials: newCredentials(conf, items),
+		}
+	}
+
+	c.jobs[j.id] = j
+
+	c.log.WithField("job_id", j.id).WithField("agents", agentsCount).Info("job dispatched")
+
+	return j, nil
+}
+
+// finish removes the job and keeps its merged report, agents which didn't report the result of the job
+// and have no newer job are free for next jobs
+func (c *Coordinator) finish(j *job) JobResult {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for _, id := range j.agents {
+		a, ok := c.agents[id]
+		if !ok || j.results[id].Done || a.job != j.id {
+			continue
+		}
+
+		a.busy = false
+
+		// the agent didn't take the job
+		select {
+		case <-a.jobCh:
+		default:
+		}
+	}
+
+	delete(c.jobs, j.id)
+
+	result := j.result()
+	result.Done = true
+
+	c.finished[j.id] = result
+
+	if len(c.finished) > finishedJobsLimit {
+		oldest := j.id
+		for id := range c.finished {
+			if id < oldest {
+				oldest = id
+			}
+		}
+
+		delete(c.finished, oldest)
+	}
+
+	return result
+}
+
+// removeDeadAgents removes agents which don't poll jobs, c.mx must be locked
+func (c *Coordinator) removeDeadAgents() {
+	for id, a := range c.agents {
+		if a.alive() || a.busy {
+			continue
+		}
+
+		delete(c.agents, id)
+
+		c.log.WithField("agent_id", id).WithField("name", a.name).Info("agent removed")
+	}
+}
+
+func (a *agent) alive() bool {
+	return time.Since(a.lastSeen) < agentTTL
+}
+
+// result returns the merged report of the job, c.mx must be locked
+func (j *job) result() JobResult {
+	result := JobResult{
+		ID:     j.id,
+		Agents: make([]AgentResult, 0, len(j.agents)),
+	}
+
+	reports := make([]map[string]tester.Item, 0, len(j.agents))
+	verdicts := make([]map[string]tester.BudgetVerdict, 0, len(j.agents))
+
+	for _, id := range j.agents {
+		agentResult := *j.results[id]
+
+		result.Agents = append(result.Agents, agentResult)
+		reports = append(reports, agentResult.Report)
+		verdicts = append(verdicts, agentResult.Verdicts)
+	}
+
+	result.Report = mergeReports(reports...)
+	result.Verdicts = mergeVerdicts(verdicts...)
+
+	if j.mix {
+		result.Mix = tester.MixReport(j.items, tester.ReportKeys(result.Report))
+	}
+
+	return result
+}
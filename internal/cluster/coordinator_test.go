@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/tester"
+)
+
+// TestCoordinatorFinishKeepsNewerJob checks that finishing of the job after its deadline doesn't free
+// the agent which took the newer job and doesn't drop the newer job
+func TestCoordinatorFinishKeepsNewerJob(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+
+	c := NewCoordinator(log, time.Millisecond)
+
+	late := c.Register("late")
+	fast := c.Register("fast")
+
+	first, err := c.dispatch(tester.Configuration{}, nil, 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []uint64{late, fast} {
+		job, err := c.NextJob(context.Background(), id)
+		if err != nil || job == nil || job.ID != first.id {
+			t.Fatalf("agent %d: job %v, error %v, expected the job %d", id, job, err, first.id)
+		}
+	}
+
+	// the fast agent reports the result and takes the newer job, the late agent is still running
+	err = c.Report(fast, &AgentReport{JobID: first.id, Done: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := c.dispatch(tester.Configuration{}, nil, 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := c.finish(first)
+	if !result.Done || !result.Agents[1].Done || result.Agents[0].Done {
+		t.Fatalf("finished job: %+v", result)
+	}
+
+	// the late agent is free, the fast agent keeps the newer job
+	_, err = c.dispatch(tester.Configuration{}, nil, 2, time.Second)
+	if err != ErrNotEnoughAgents {
+		t.Fatalf("dispatch to the busy agent: error %v, expected %v", err, ErrNotEnoughAgents)
+	}
+
+	job, err := c.NextJob(context.Background(), fast)
+	if err != nil || job == nil || job.ID != second.id {
+		t.Fatalf("fast agent: job %v, error %v, expected the job %d", job, err, second.id)
+	}
+
+	// the job which the agent didn't receive is returned to the agent
+	c.Requeue(fast, job)
+
+	job, err = c.NextJob(context.Background(), fast)
+	if err != nil || job == nil || job.ID != second.id {
+		t.Fatalf("requeued job: %v, error %v, expected the job %d", job, err, second.id)
+	}
+
+	third, err := c.dispatch(tester.Configuration{}, nil, 1, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err = c.NextJob(context.Background(), late)
+	if err != nil || job == nil || job.ID != third.id {
+		t.Fatalf("late agent: job %v, error %v, expected the job %d", job, err, third.id)
+	}
+}
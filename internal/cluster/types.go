@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/tagirmukail/ldtester/internal/auth"
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
+	"github.com/tagirmukail/ldtester/internal/selfmon"
+	"github.com/tagirmukail/ldtester/internal/tester"
+	"github.com/tagirmukail/ldtester/internal/url_item"
+)
+
+// Job represents the share of the distributed load test for one agent
+type Job struct {
+	ID          uint64               `json:"id"`
+	AgentIndex  int                  `json:"agent_index"`
+	AgentsCount int                  `json:"agents_count"`
+	StartAt     time.Time            `json:"start_at"` // all agents start at the same time, their clocks must be synchronized
+	Timeout     time.Duration        `json:"timeout"`
+	Config      tester.Configuration `json:"load_test_config"` // redacted, secrets are in Credentials
+	Items       []url_item.Item      `json:"items"`            // redacted, secrets are in Credentials
+	Credentials *Credentials         `json:"credentials,omitempty"`
+}
+
+// Credentials represents secrets of the job which are sent to the agent only, they are never stored or echoed
+type Credentials struct {
+	Auth  auth.Options            `json:"auth"`
+	Proxy proxyconf.Options       `json:"proxy"`
+	Items map[int]ItemCredentials `json:"items,omitempty"` // secrets of urls by their indexes
+}
+
+// ItemCredentials represents secrets of the url
+type ItemCredentials struct {
+	Auth  *auth.Options      `json:"auth,omitempty"`
+	Proxy *proxyconf.Options `json:"proxy,omitempty"`
+}
+
+// newCredentials returns secrets of the configuration and urls
+func newCredentials(conf tester.Configuration, items []url_item.Item) *Credentials {
+	c := &Credentials{Auth: conf.Auth, Proxy: conf.Proxy}
+
+	for i, item := range items {
+		if item.Auth == nil && item.Proxy == nil {
+			continue
+		}
+
+		if c.Items == nil {
+			c.Items = make(map[int]ItemCredentials)
+		}
+
+		c.Items[i] = ItemCredentials{Auth: item.Auth, Proxy: item.Proxy}
+	}
+
+	return c
+}
+
+// apply returns the configuration and urls of the job with secrets of credentials
+func (c *Credentials) apply(conf tester.Configuration, items []url_item.Item) (tester.Configuration, []url_item.Item) {
+	if c == nil {
+		return conf, items
+	}
+
+	conf.Auth = c.Auth
+	conf.Proxy = c.Proxy
+
+	result := make([]url_item.Item, 0, len(items))
+
+	for i, item := range items {
+		if secrets, ok := c.Items[i]; ok {
+			item.Auth, item.Proxy = secrets.Auth, secrets.Proxy
+		}
+
+		result = append(result, item)
+	}
+
+	return conf, result
+}
+
+// AgentReport represents the current or the final report of the job from the agent
+type AgentReport struct {
+	JobID     uint64                          `json:"job_id"`
+	Done      bool                            `json:"done"`
+	Error     string                          `json:"error,omitempty"`
+	Report    map[string]tester.Item          `json:"report"`
+	Generator *selfmon.Report                 `json:"generator,omitempty"`
+	Verdicts  map[string]tester.BudgetVerdict `json:"verdicts,omitempty"`
+}
+
+// AgentInfo represents the registered agent
+type AgentInfo struct {
+	ID       uint64    `json:"id"`
+	Name     string    `json:"name"`
+	LastSeen time.Time `json:"last_seen"`
+	Alive    bool      `json:"alive"`
+	Busy     bool      `json:"busy"`
+}
+
+// AgentResult represents the result of the job from one agent
+type AgentResult struct {
+	AgentID   uint64                          `json:"agent_id"`
+	Name      string                          `json:"name"`
+	Done      bool                            `json:"done"`
+	Error     string                          `json:"error,omitempty"`
+	Report    map[string]tester.Item          `json:"report"`
+	Generator *selfmon.Report                 `json:"generator,omitempty"` // resources usage of the agent
+	Verdicts  map[string]tester.BudgetVerdict `json:"verdicts,omitempty"`
+}
+
+// JobResult represents the merged report of all agents
+type JobResult struct {
+	ID     uint64                 `json:"id"`
+	Done   bool                   `json:"done"` // all agents reported their results or the job deadline is passed
+	Report map[string]tester.Item `json:"report"`
+	Agents []AgentResult          `json:"agents"`
+
+	Mix      map[string]tester.MixItem       `json:"mix,omitempty"`
+	Verdicts map[string]tester.BudgetVerdict `json:"verdicts,omitempty"`
+}
+
+// StartResponse represents the id of the started job
+type StartResponse struct {
+	ID uint64 `json:"id"`
+}
+
+// response represents the response of the coordinator api
+type response struct {
+	Message string              `json:"message"`
+	Data    jsoniter.RawMessage `json:"data"`
+}
+
+// RegisterRequest represents the registration of the agent
+type RegisterRequest struct {
+	Name string `json:"name"`
+}
+
+// RegisterResponse represents the id of the registered agent
+type RegisterResponse struct {
+	ID uint64 `json:"id"`
+}
+
+// mergeReports merges reports of the same urls from several agents
+func mergeReports(reports ...map[string]tester.Item) map[string]tester.Item {
+	result := make(map[string]tester.Item)
+
+	for _, report := range reports {
+		for url, item := range report {
+			existItem, ok := result[url]
+			if !ok {
+				result[url] = item.Clone()
+				continue
+			}
+
+			result[url] = existItem.Merge(item)
+		}
+	}
+
+	return result
+}
+
+// mergeVerdicts merges verdicts of the same budgets from several agents
+func mergeVerdicts(verdicts ...map[string]tester.BudgetVerdict) map[string]tester.BudgetVerdict {
+	result := make(map[string]tester.BudgetVerdict)
+
+	for _, v := range verdicts {
+		for budget, verdict := range v {
+			existVerdict, ok := result[budget]
+			if !ok {
+				result[budget] = verdict
+				continue
+			}
+
+			result[budget] = existVerdict.Merge(verdict)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,153 @@
+package dialer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// Modes of local addresses
+const (
+	ModeRoundRobin = "round_robin" // new connections rotate local addresses
+	ModePerVU      = "per_vu"      // the virtual user keeps its local address
+)
+
+// Dialing of target connections, they are shared by the resolver, the proxy and the system dialer
+const (
+	Timeout   = 30 * time.Second
+	KeepAlive = 30 * time.Second
+)
+
+var errInvalidDial = errors.New("invalid dial options")
+
+// DialFunc dials connections of the transport, the resolver and the proxy
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dial dials the connection by the background context, it is required by proxy dialers
+func (d DialFunc) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d DialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
+
+// Options represents local source addresses of target connections or the unix socket of targets,
+// the system dialer is used if options are empty
+type Options struct {
+	LocalAddrs    []string `json:"local_addrs,omitempty"`     // local ip addresses of connections
+	LocalAddrMode string   `json:"local_addr_mode,omitempty"` // round_robin or per_vu, round_robin if empty
+	UnixSocket    string   `json:"unix_socket,omitempty"`     // path of the socket, urls set the Host header only
+}
+
+func FromConfig(cfg config.Dial) Options {
+	return Options{
+		LocalAddrs:    cfg.LocalAddrs,
+		LocalAddrMode: cfg.LocalAddrMode,
+		UnixSocket:    cfg.UnixSocket,
+	}
+}
+
+// Empty returns true if the system dialer is used
+func (o Options) Empty() bool {
+	return len(o.LocalAddrs) == 0 && o.UnixSocket == ""
+}
+
+// Validate checks local addresses and the mode
+func (o Options) Validate() error {
+	_, err := o.localAddrs()
+	if err != nil {
+		return err
+	}
+
+	switch o.LocalAddrMode {
+	case "", ModeRoundRobin, ModePerVU:
+	default:
+		return fmt.Errorf("%w: unknown local address mode %q, expected round_robin or per_vu",
+			errInvalidDial, o.LocalAddrMode)
+	}
+
+	if o.UnixSocket != "" && len(o.LocalAddrs) > 0 {
+		return fmt.Errorf("%w: local addresses can't be used with the unix socket", errInvalidDial)
+	}
+
+	return nil
+}
+
+// Sources returns the number of transports of the url: every local address has its own transport
+// in the per_vu mode, so connections of virtual users are not shared
+func (o Options) Sources() int {
+	if o.LocalAddrMode == ModePerVU && len(o.LocalAddrs) > 1 {
+		return len(o.LocalAddrs)
+	}
+
+	return 1
+}
+
+// Dial returns the dial func of the transport with the index of the source, nil if the system dialer is used
+func (o Options) Dial(source int) (DialFunc, error) {
+	if o.UnixSocket != "" {
+		d := New(nil)
+
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", o.UnixSocket)
+		}, nil
+	}
+
+	addrs, err := o.localAddrs()
+	if err != nil || len(addrs) == 0 {
+		return nil, err
+	}
+
+	if o.Sources() > 1 {
+		return New(addrs[source%len(addrs)]).DialContext, nil
+	}
+
+	dialers := make([]*net.Dialer, 0, len(addrs))
+	for _, addr := range addrs {
+		dialers = append(dialers, New(addr))
+	}
+
+	var next uint64
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := dialers[(atomic.AddUint64(&next, 1)-1)%uint64(len(dialers))]
+
+		return d.DialContext(ctx, network, addr)
+	}, nil
+}
+
+// localAddrs returns parsed local addresses
+func (o Options) localAddrs() ([]net.IP, error) {
+	result := make([]net.IP, 0, len(o.LocalAddrs))
+
+	for _, addr := range o.LocalAddrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("%w: invalid local address %q", errInvalidDial, addr)
+		}
+
+		result = append(result, ip)
+	}
+
+	return result, nil
+}
+
+// New returns the dialer of connections from the local address ip, the system address if ip is nil
+func New(ip net.IP) *net.Dialer {
+	d := &net.Dialer{
+		Timeout:   Timeout,
+		KeepAlive: KeepAlive,
+	}
+
+	if ip != nil {
+		d.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	return d
+}
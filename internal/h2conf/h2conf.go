@@ -0,0 +1,250 @@
+package h2conf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+var errInvalidHTTP2 = errors.New("invalid http/2 options: negative values")
+
+// Options represents http/2 connections of targets
+type Options struct {
+	H2C          bool          `json:"h2c,omitempty"`           // http urls use http/2 with prior knowledge over tcp
+	MaxInFlight  int           `json:"max_in_flight,omitempty"` // client side cap of in-flight requests of the connection group
+	Connections  int           `json:"connections,omitempty"`   // connections of the host, new connections are opened by need if 0
+	PingInterval time.Duration `json:"ping_interval,omitempty"` // ping of connections without frames, disabled if 0
+	PingTimeout  time.Duration `json:"ping_timeout,omitempty"`  // the connection is closed without the ping response
+}
+
+func FromConfig(cfg config.HTTP2) Options {
+	return Options{
+		H2C:          cfg.H2C,
+		MaxInFlight:  cfg.MaxInFlight,
+		Connections:  cfg.Connections,
+		PingInterval: time.Duration(cfg.PingInterval) * time.Second,
+		PingTimeout:  time.Duration(cfg.PingTimeout) * time.Second,
+	}
+}
+
+// Validate checks limits of streams and connections
+func (o Options) Validate() error {
+	if o.MaxInFlight < 0 || o.Connections < 0 || o.PingInterval < 0 || o.PingTimeout < 0 {
+		return errInvalidHTTP2
+	}
+
+	return nil
+}
+
+// Transport returns the round tripper of http/2 connections of tr, tr is configured for http/2 over tls and
+// requests are distributed across Connections copies of tr
+func (o Options) Transport(tr *http.Transport) (http.RoundTripper, error) {
+	count := o.Connections
+	if count == 0 {
+		count = 1
+	}
+
+	// copies are made before tr is configured, so they have their own connection pools
+	transports := make([]*http.Transport, 0, count)
+	transports = append(transports, tr)
+
+	for i := 1; i < count; i++ {
+		transports = append(transports, tr.Clone())
+	}
+
+	result := &roundRobin{rts: make([]http.RoundTripper, 0, count)}
+
+	for _, t1 := range transports {
+		rt, err := o.configure(t1)
+		if err != nil {
+			return nil, err
+		}
+
+		result.rts = append(result.rts, rt)
+	}
+
+	if len(result.rts) == 1 {
+		return result.rts[0], nil
+	}
+
+	return result, nil
+}
+
+// configure enables http/2 of t1, in-flight requests of t1 are capped by MaxInFlight: t1 has one connection
+// if Connections is set, so the cap is the cap of the connection, otherwise it is shared by connections of t1
+func (o Options) configure(t1 *http.Transport) (http.RoundTripper, error) {
+	t2, err := http2.ConfigureTransports(t1)
+	if err != nil {
+		return nil, err
+	}
+
+	o.apply(t2)
+
+	var rt http.RoundTripper = t1
+
+	if o.H2C {
+		dial := t1.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+
+		h2c := &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: t1.DisableCompression,
+		}
+
+		// connections are dialed by the pool with contexts of requests
+		h2c.ConnPool = &cleartextPool{t2: h2c, dial: dial, conns: make(map[string][]*http2.ClientConn)}
+
+		o.apply(h2c)
+
+		rt = &cleartext{tls: t1, h2c: h2c}
+	}
+
+	if o.MaxInFlight > 0 {
+		rt = &inFlight{rt: rt, slots: make(chan struct{}, o.MaxInFlight)}
+	}
+
+	return rt, nil
+}
+
+func (o Options) apply(t2 *http2.Transport) {
+	t2.ReadIdleTimeout = o.PingInterval
+	t2.PingTimeout = o.PingTimeout
+
+	// fixed connections don't grow when streams of the server are exhausted
+	t2.StrictMaxConcurrentStreams = o.Connections > 0
+}
+
+// cleartext sends requests of http urls by h2c
+type cleartext struct {
+	tls http.RoundTripper
+	h2c http.RoundTripper
+}
+
+func (c *cleartext) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		return c.h2c.RoundTrip(req)
+	}
+
+	return c.tls.RoundTrip(req)
+}
+
+// cleartextPool dials h2c connections by the dial func of the url with the context of the request,
+// the connection is reused while it can take new requests
+type cleartextPool struct {
+	t2   *http2.Transport
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	mx    sync.Mutex
+	conns map[string][]*http2.ClientConn
+}
+
+func (p *cleartextPool) GetClientConn(req *http.Request, addr string) (*http2.ClientConn, error) {
+	p.mx.Lock()
+	for _, cc := range p.conns[addr] {
+		if cc.CanTakeNewRequest() {
+			p.mx.Unlock()
+			return cc, nil
+		}
+	}
+	p.mx.Unlock()
+
+	conn, err := p.dial(req.Context(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := p.t2.NewClientConn(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	p.mx.Lock()
+	p.conns[addr] = append(p.conns[addr], cc)
+	p.mx.Unlock()
+
+	return cc, nil
+}
+
+// MarkDead removes the closed connection from the pool
+func (p *cleartextPool) MarkDead(cc *http2.ClientConn) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	for addr, conns := range p.conns {
+		for i, c := range conns {
+			if c != cc {
+				continue
+			}
+
+			p.conns[addr] = append(conns[:i:i], conns[i+1:]...)
+			if len(p.conns[addr]) == 0 {
+				delete(p.conns, addr)
+			}
+
+			return
+		}
+	}
+}
+
+// inFlight caps in-flight requests of the round tripper on the client side, the request waits for the free slot,
+// the concurrent streams limit of the server isn't changed
+type inFlight struct {
+	rt    http.RoundTripper
+	slots chan struct{}
+}
+
+func (s *inFlight) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case s.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := s.rt.RoundTrip(req)
+	if err != nil {
+		<-s.slots
+		return nil, err
+	}
+
+	// the stream is open until the body is closed
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: func() { <-s.slots }}
+
+	return resp, nil
+}
+
+// releaseBody releases the slot of the stream on the first close of the body
+type releaseBody struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+
+	return err
+}
+
+// roundRobin distributes requests across round trippers
+type roundRobin struct {
+	rts  []http.RoundTripper
+	next uint64
+}
+
+func (r *roundRobin) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.rts[(atomic.AddUint64(&r.next, 1)-1)%uint64(len(r.rts))].RoundTrip(req)
+}
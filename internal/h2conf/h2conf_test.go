@@ -0,0 +1,78 @@
+package h2conf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// TestH2C checks that h2c connections are dialed by the dial func of the transport with contexts of requests
+// and reused by requests
+func TestH2C(t *testing.T) {
+	server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), &http2.Server{}))
+	defer server.Close()
+
+	var dials int32
+
+	tr := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+
+	rt, err := Options{H2C: true}.Transport(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.Proto != "HTTP/2.0" {
+			t.Fatalf("protocol %s, expected HTTP/2.0", resp.Proto)
+		}
+	}
+
+	if dials != 1 {
+		t.Fatalf("%d dials, expected the reused connection", dials)
+	}
+
+	// the canceled request doesn't dial
+	rt, err = Options{H2C: true}.Transport(tr.Clone())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = rt.RoundTrip(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled request: error %v, expected %v", err, context.Canceled)
+	}
+}
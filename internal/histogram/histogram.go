@@ -0,0 +1,171 @@
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+const (
+	subBucketBits  = 5 // 32 linear sub buckets in every power of two, the precision is ~3%
+	subBucketCount = 1 << subBucketBits
+)
+
+// Histogram represents log-linear histogram of durations with microsecond resolution.
+// All histograms have the same buckets layout, so they can be merged without precision loss.
+// Histogram is not safe for concurrent use.
+type Histogram struct {
+	Buckets map[int]uint64 `json:"buckets"`
+	Count   uint64         `json:"count"`
+	Sum     time.Duration  `json:"sum"`
+	Min     time.Duration  `json:"min"`
+	Max     time.Duration  `json:"max"`
+}
+
+func New() *Histogram {
+	return &Histogram{
+		Buckets: make(map[int]uint64),
+	}
+}
+
+func (h *Histogram) Record(d time.Duration) {
+	h.RecordN(d, 1)
+}
+
+// RecordN records the duration n times
+func (h *Histogram) RecordN(d time.Duration, n uint64) {
+	if n == 0 {
+		return
+	}
+
+	if d < 0 {
+		d = 0
+	}
+
+	if h.Buckets == nil {
+		h.Buckets = make(map[int]uint64)
+	}
+
+	h.Buckets[bucketIndex(d.Microseconds())] += n
+
+	if h.Count == 0 || d < h.Min {
+		h.Min = d
+	}
+
+	if d > h.Max {
+		h.Max = d
+	}
+
+	h.Count += n
+	h.Sum += d * time.Duration(n)
+}
+
+// Merge adds all values of the other histogram to h
+func (h *Histogram) Merge(o *Histogram) {
+	if o == nil || o.Count == 0 {
+		return
+	}
+
+	if h.Buckets == nil {
+		h.Buckets = make(map[int]uint64, len(o.Buckets))
+	}
+
+	for idx, count := range o.Buckets {
+		h.Buckets[idx] += count
+	}
+
+	if h.Count == 0 || o.Min < h.Min {
+		h.Min = o.Min
+	}
+
+	if o.Max > h.Max {
+		h.Max = o.Max
+	}
+
+	h.Count += o.Count
+	h.Sum += o.Sum
+}
+
+func (h *Histogram) Clone() *Histogram {
+	c := New()
+	c.Merge(h)
+
+	return c
+}
+
+func (h *Histogram) Reset() {
+	*h = Histogram{Buckets: make(map[int]uint64)}
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h == nil || h.Count == 0 {
+		return 0
+	}
+
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns the value at the quantile q (0..1), for example 0.99 for p99
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h == nil || h.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+
+	indexes := make([]int, 0, len(h.Buckets))
+	for idx := range h.Buckets {
+		indexes = append(indexes, idx)
+	}
+
+	sort.Ints(indexes)
+
+	var seen uint64
+	for _, idx := range indexes {
+		seen += h.Buckets[idx]
+		if seen < rank {
+			continue
+		}
+
+		lower, upper := bucketBounds(idx)
+		value := time.Duration(lower+(upper-lower)/2) * time.Microsecond
+
+		switch {
+		case value < h.Min:
+			return h.Min
+		case value > h.Max:
+			return h.Max
+		default:
+			return value
+		}
+	}
+
+	return h.Max
+}
+
+func bucketIndex(v int64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+
+	shift := bits.Len64(uint64(v)) - 1 - subBucketBits
+	sub := int(v>>uint(shift)) - subBucketCount
+
+	return (shift+1)*subBucketCount + sub
+}
+
+// bucketBounds returns the lowest and the highest value of the bucket
+func bucketBounds(idx int) (int64, int64) {
+	if idx < subBucketCount {
+		return int64(idx), int64(idx)
+	}
+
+	shift := idx/subBucketCount - 1
+	lower := int64(idx%subBucketCount+subBucketCount) << uint(shift)
+
+	return lower, lower + int64(1)<<uint(shift) - 1
+}
@@ -0,0 +1,96 @@
+package histogram
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// values returns count durations from 1µs to ~10s spread over several powers of two
+func values(count, seed int) []time.Duration {
+	result := make([]time.Duration, 0, count)
+
+	for n := 0; n < count; n++ {
+		exp := float64((n*37+seed*11)%1000) / 1000 * 7 // 10^0..10^7 µs
+		result = append(result, time.Duration(math.Pow(10, exp))*time.Microsecond)
+	}
+
+	return result
+}
+
+// TestMerge checks that merged histograms equal the histogram of all values
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name  string
+		parts [][]time.Duration
+	}{
+		{"two parts", [][]time.Duration{values(1000, 1), values(500, 2)}},
+		{"empty part", [][]time.Duration{values(1000, 1), nil}},
+		{"disjoint ranges", [][]time.Duration{{time.Microsecond, 5 * time.Microsecond}, {time.Second, 3 * time.Second}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			merged, combined := New(), New()
+
+			for _, part := range c.parts {
+				h := New()
+
+				for _, d := range part {
+					h.Record(d)
+					combined.Record(d)
+				}
+
+				merged.Merge(h)
+			}
+
+			if !reflect.DeepEqual(merged, combined) {
+				t.Fatalf("merged %+v, expected %+v", merged, combined)
+			}
+
+			for _, q := range []float64{0, 0.5, 0.9, 0.99, 1} {
+				if merged.Quantile(q) != combined.Quantile(q) {
+					t.Errorf("q%v: %v, expected %v", q, merged.Quantile(q), combined.Quantile(q))
+				}
+			}
+		})
+	}
+}
+
+// TestQuantile checks quantiles against exact quantiles of sorted values within the bucket precision
+func TestQuantile(t *testing.T) {
+	all := values(10000, 3)
+
+	h := New()
+	for _, d := range all {
+		h.Record(d)
+	}
+
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	cases := []struct {
+		q     float64
+		exact time.Duration
+	}{
+		{0, all[0]},
+		{0.5, all[4999]},
+		{0.9, all[8999]},
+		{0.99, all[9899]},
+		{0.999, all[9989]},
+		{1, all[9999]},
+	}
+
+	for _, c := range cases {
+		got := h.Quantile(c.q)
+
+		if diff := math.Abs(float64(got-c.exact)) / float64(c.exact); diff > 1.0/subBucketCount {
+			t.Errorf("q%v: %v, expected %v within %.1f%%", c.q, got, c.exact, 100.0/subBucketCount)
+		}
+	}
+
+	if h.Mean() != time.Duration(int64(h.Sum)/int64(len(all))) || h.Min != all[0] || h.Max != all[len(all)-1] {
+		t.Fatalf("mean %v, min %v, max %v", h.Mean(), h.Min, h.Max)
+	}
+}
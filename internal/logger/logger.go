@@ -2,7 +2,7 @@ package logger
 
 import (
 	"context"
-	"os"
+	"io"
 
 	"github.com/sirupsen/logrus"
 )
@@ -13,7 +13,7 @@ type Logger interface {
 	GetLevel() logrus.Level
 }
 
-func New(ctx context.Context, level logrus.Level, output *os.File) Logger {
+func New(ctx context.Context, level logrus.Level, output io.Writer) Logger {
 	log := logrus.New()
 	log.SetLevel(level)
 	log.SetOutput(output)
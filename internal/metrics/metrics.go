@@ -0,0 +1,188 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/tagirmukail/ldtester/internal/cache"
+	"github.com/tagirmukail/ldtester/internal/tester"
+)
+
+const (
+	namespace = "ldtester"
+
+	statusError = "error"
+)
+
+// Metrics represents prometheus metrics of the server and live load tests,
+// it implements tester.Observer
+type Metrics struct {
+	registry *prometheus.Registry
+
+	runningJobs     prometheus.Gauge
+	queuedJobs      prometheus.Gauge
+	handlerDuration *prometheus.HistogramVec
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	inFlightRequests *prometheus.GaugeVec
+	concurrency      *prometheus.GaugeVec
+}
+
+func New(c *cache.Cache) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		runningJobs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "running_jobs",
+			Help:      "Number of running load tests.",
+		}),
+		queuedJobs: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "queued_jobs",
+			Help:      "Number of load tests waiting for start.",
+		}),
+		handlerDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_handler_duration_seconds",
+			Help:      "Duration of the server handlers.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 4, 10),
+		}, []string{"handler", "method", "code"}),
+
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "test_requests_total",
+			Help:      "Number of load test requests by url and response status, status is error for failed requests.",
+		}, []string{"url", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "test_request_duration_seconds",
+			Help:      "Duration of load test requests.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 15),
+		}, []string{"url"}),
+		inFlightRequests: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "test_in_flight_requests",
+			Help:      "Number of load test requests waiting for response.",
+		}, []string{"url"}),
+		concurrency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "test_concurrency",
+			Help:      "Current concurrency level of the load test worker.",
+		}, []string{"url"}),
+	}
+
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+
+		m.runningJobs,
+		m.queuedJobs,
+		m.handlerDuration,
+
+		m.requests,
+		m.requestDuration,
+		m.inFlightRequests,
+		m.concurrency,
+
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cache_size",
+			Help:      "Number of items in the results cache.",
+		}, func() float64 { return float64(c.Len()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Number of urls found in the results cache.",
+		}, func() float64 { return float64(c.Hits()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Number of urls not found in the results cache.",
+		}, func() float64 { return float64(c.Misses()) }),
+	)
+
+	return m
+}
+
+// Handler returns http handler for the metrics scraping
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Middleware measures duration of the server handlers
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, req)
+
+		handler := req.URL.Path
+		if route := mux.CurrentRoute(req); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				handler = tpl
+			}
+		}
+
+		m.handlerDuration.
+			WithLabelValues(handler, req.Method, strconv.Itoa(sw.status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *Metrics) JobQueued() {
+	m.queuedJobs.Inc()
+}
+
+// JobCanceled removes the queued job which is not started
+func (m *Metrics) JobCanceled() {
+	m.queuedJobs.Dec()
+}
+
+// JobStarted moves the queued job to the running jobs
+func (m *Metrics) JobStarted() {
+	m.queuedJobs.Dec()
+	m.runningJobs.Inc()
+}
+
+func (m *Metrics) JobFinished() {
+	m.runningJobs.Dec()
+}
+
+func (m *Metrics) ConcurrencyChanged(key tester.Key, concurrency int) {
+	m.concurrency.WithLabelValues(key.URL).Set(float64(concurrency))
+}
+
+func (m *Metrics) RequestStarted(key tester.Key) {
+	m.inFlightRequests.WithLabelValues(key.URL).Inc()
+}
+
+func (m *Metrics) RequestFinished(key tester.Key, sample tester.Sample) {
+	m.inFlightRequests.WithLabelValues(key.URL).Dec()
+
+	status := statusError
+	if sample.Err == nil {
+		status = strconv.Itoa(sample.StatusCode)
+	}
+
+	m.requests.WithLabelValues(key.URL, status).Inc()
+	m.requestDuration.WithLabelValues(key.URL).Observe(sample.Duration.Seconds())
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,99 @@
+package outputs
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// graphite writes points in Graphite plaintext protocol `path value timestamp` over tcp
+type graphite struct {
+	addr   string
+	prefix string
+}
+
+func newGraphite(cfg config.GraphiteOutput) *graphite {
+	return &graphite{
+		addr:   cfg.Addr,
+		prefix: cfg.Prefix,
+	}
+}
+
+func (o *graphite) Name() string {
+	return "graphite"
+}
+
+func (o *graphite) Write(ctx context.Context, points []Point) error {
+	dialer := net.Dialer{}
+
+	conn, err := dialer.DialContext(ctx, "tcp", o.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetWriteDeadline(deadline)
+	}
+
+	b := strings.Builder{}
+
+	for _, p := range points {
+		timestamp := strconv.FormatInt(p.Time.Unix(), 10)
+
+		for _, field := range p.Fields {
+			b.WriteString(metricPath(o.prefix, p, field))
+			b.WriteString(" ")
+			b.WriteString(strconv.FormatFloat(field.Value, 'f', -1, 64))
+			b.WriteString(" ")
+			b.WriteString(timestamp)
+			b.WriteString("\n")
+		}
+	}
+
+	_, err = conn.Write([]byte(b.String()))
+
+	return err
+}
+
+func (o *graphite) Close() error {
+	return nil
+}
+
+// metricPath returns dot separated path of the field `prefix.measurement.tag_value.field`,
+// url tags are sanitized to one path node
+func metricPath(prefix string, p Point, field Field) string {
+	nodes := make([]string, 0, len(p.Tags)+3)
+
+	if prefix != "" {
+		nodes = append(nodes, prefix)
+	}
+
+	nodes = append(nodes, p.Measurement)
+
+	for _, tag := range p.Tags {
+		if tag.Name == "host" {
+			continue // the host is a part of the url
+		}
+
+		nodes = append(nodes, sanitizePathNode(tag.Value))
+	}
+
+	nodes = append(nodes, field.Name)
+
+	return strings.Join(nodes, ".")
+}
+
+func sanitizePathNode(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,156 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	nurl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+const udpPacketSize = 1400
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// influxDB writes points in InfluxDB line protocol over http or udp
+type influxDB struct {
+	url     string
+	udpConn net.Conn
+	client  *http.Client
+}
+
+func newInfluxDB(cfg config.InfluxDBOutput) (*influxDB, error) {
+	parsedURL, err := nurl.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch parsedURL.Scheme {
+	case "udp":
+		conn, err := net.Dial("udp", parsedURL.Host)
+		if err != nil {
+			return nil, err
+		}
+
+		return &influxDB{url: cfg.URL, udpConn: conn}, nil
+	case "http", "https":
+		return &influxDB{url: cfg.URL, client: &http.Client{Timeout: writeTimeout}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported influxdb url scheme: %s", parsedURL.Scheme)
+	}
+}
+
+func (o *influxDB) Name() string {
+	return "influxdb"
+}
+
+func (o *influxDB) Write(ctx context.Context, points []Point) error {
+	lines := make([]string, 0, len(points))
+	for _, p := range points {
+		lines = append(lines, influxLine(p))
+	}
+
+	if o.udpConn != nil {
+		return writePackets(o.udpConn, lines)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		return err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("influxdb responded %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+func (o *influxDB) Close() error {
+	if o.udpConn != nil {
+		return o.udpConn.Close()
+	}
+
+	return nil
+}
+
+// influxLine formats the point as `measurement,tag=value field=value timestamp`, tags are sorted by name
+func influxLine(p Point) string {
+	b := strings.Builder{}
+
+	b.WriteString(influxMeasurementEscaper.Replace(p.Measurement))
+
+	tags := append([]Tag(nil), p.Tags...)
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	for _, tag := range tags {
+		b.WriteString(",")
+		b.WriteString(influxTagEscaper.Replace(tag.Name))
+		b.WriteString("=")
+		b.WriteString(influxTagEscaper.Replace(tag.Value))
+	}
+
+	for i, field := range p.Fields {
+		if i == 0 {
+			b.WriteString(" ")
+		} else {
+			b.WriteString(",")
+		}
+
+		b.WriteString(influxTagEscaper.Replace(field.Name))
+		b.WriteString("=")
+		b.WriteString(strconv.FormatFloat(field.Value, 'f', -1, 64))
+	}
+
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatInt(p.Time.UnixNano(), 10))
+
+	return b.String()
+}
+
+// writePackets writes lines to the udp connection, joining them into packets up to udpPacketSize
+func writePackets(conn net.Conn, lines []string) error {
+	packet := bytes.Buffer{}
+
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > udpPacketSize {
+			_, err := conn.Write(packet.Bytes())
+			if err != nil {
+				return err
+			}
+
+			packet.Reset()
+		}
+
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+
+		packet.WriteString(line)
+	}
+
+	if packet.Len() == 0 {
+		return nil
+	}
+
+	_, err := conn.Write(packet.Bytes())
+
+	return err
+}
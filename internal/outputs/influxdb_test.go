@@ -0,0 +1,98 @@
+package outputs
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+var influxPoints = []Point{
+	{
+		Measurement: requestsMeasurement,
+		Tags:        []Tag{{Name: "host", Value: "test.com"}, {Name: "url", Value: "http://test.com/a b"}},
+		Fields:      []Field{{Name: "requests", Value: 10, Counter: true}, {Name: "latency_p99", Value: 0.25}},
+		Time:        time.Unix(1700000000, 0),
+	},
+	{
+		Measurement: responsesMeasurement,
+		Tags:        []Tag{{Name: "host", Value: "test.com"}, {Name: "url", Value: "http://test.com/a b"}, {Name: "status", Value: "200"}},
+		Fields:      []Field{{Name: "count", Value: 9, Counter: true}},
+		Time:        time.Unix(1700000000, 0),
+	},
+}
+
+var influxLines = []string{
+	`ldtester_requests,host=test.com,url=http://test.com/a\ b requests=10,latency_p99=0.25 1700000000000000000`,
+	`ldtester_responses,host=test.com,status=200,url=http://test.com/a\ b count=9 1700000000000000000`,
+}
+
+// TestInfluxDBHTTP writes points to the stand-in http write endpoint
+func TestInfluxDBHTTP(t *testing.T) {
+	received := make(chan string, 1)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	o, err := newInfluxDB(config.InfluxDBOutput{URL: receiver.URL + "/write?db=ldtester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	err = o.Write(context.Background(), influxPoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := <-received, strings.Join(influxLines, "\n"); got != expected {
+		t.Fatalf("lines:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+// TestInfluxDBUDP writes points to the stand-in udp listener
+func TestInfluxDBUDP(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	o, err := newInfluxDB(config.InfluxDBOutput{URL: "udp://" + listener.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	err = o.Write(context.Background(), influxPoints)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packet := make([]byte, udpPacketSize)
+
+	n, _, err := listener.ReadFrom(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, expected := string(packet[:n]), strings.Join(influxLines, "\n"); got != expected {
+		t.Fatalf("lines:\n%s\nexpected:\n%s", got, expected)
+	}
+}
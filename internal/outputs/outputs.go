@@ -0,0 +1,289 @@
+package outputs
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+	"github.com/tagirmukail/ldtester/internal/histogram"
+	"github.com/tagirmukail/ldtester/internal/tester"
+)
+
+const (
+	defaultInterval = 10 * time.Second
+	writeTimeout    = 5 * time.Second
+
+	requestsMeasurement  = "ldtester_requests"
+	responsesMeasurement = "ldtester_responses"
+)
+
+// Output represents time-series backend
+type Output interface {
+	Name() string
+	Write(ctx context.Context, points []Point) error
+	Close() error
+}
+
+// Point represents aggregated metrics of one url for one interval
+type Point struct {
+	Measurement string
+	Tags        []Tag // in the order of graphite path nodes, label encoders sort them by name
+	Fields      []Field
+	Time        time.Time
+}
+
+type Tag struct {
+	Name  string
+	Value string
+}
+
+type Field struct {
+	Name    string
+	Value   float64
+	Counter bool // the value is a count of events in the interval, remote write sends its cumulative total
+}
+
+// Pusher aggregates metrics of the live load test and pushes them to outputs every interval,
+// it implements tester.Observer
+type Pusher struct {
+	log logrus.FieldLogger
+
+	interval time.Duration
+	outputs  []Output
+
+	mx      sync.Mutex
+	windows map[tester.Key]*window
+	last    time.Time
+
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+// window represents metrics of one url for the current interval
+type window struct {
+	requests    uint64
+	errors      uint64
+	statuses    map[int]uint64
+	latency     *histogram.Histogram
+	concurrency int
+	inFlight    int
+}
+
+func newWindow() *window {
+	return &window{
+		statuses: make(map[int]uint64),
+		latency:  histogram.New(),
+	}
+}
+
+func New(cfg config.Outputs, log logrus.FieldLogger) (*Pusher, error) {
+	p := &Pusher{
+		log:      log,
+		interval: time.Duration(cfg.Interval) * time.Second,
+		windows:  make(map[tester.Key]*window),
+		stopCh:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if p.interval <= 0 {
+		p.interval = defaultInterval
+	}
+
+	if cfg.InfluxDB.URL != "" {
+		o, err := newInfluxDB(cfg.InfluxDB)
+		if err != nil {
+			return nil, err
+		}
+
+		p.outputs = append(p.outputs, o)
+	}
+
+	if cfg.Graphite.Addr != "" {
+		p.outputs = append(p.outputs, newGraphite(cfg.Graphite))
+	}
+
+	if cfg.StatsD.Addr != "" {
+		o, err := newStatsD(cfg.StatsD)
+		if err != nil {
+			p.closeOutputs()
+			return nil, err
+		}
+
+		p.outputs = append(p.outputs, o)
+	}
+
+	if cfg.PrometheusRemoteWrite.URL != "" {
+		p.outputs = append(p.outputs, newRemoteWrite(cfg.PrometheusRemoteWrite))
+	}
+
+	return p, nil
+}
+
+// Start starts pushing metrics every interval
+func (p *Pusher) Start() {
+	p.last = time.Now()
+
+	go func() {
+		defer close(p.done)
+
+		t := time.NewTicker(p.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				p.flush()
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop pushes metrics of the last interval and closes outputs
+func (p *Pusher) Stop() {
+	close(p.stopCh)
+	<-p.done
+
+	p.flush()
+	p.closeOutputs()
+}
+
+func (p *Pusher) ConcurrencyChanged(key tester.Key, concurrency int) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.window(key).concurrency = concurrency
+}
+
+func (p *Pusher) RequestStarted(key tester.Key) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	p.window(key).inFlight++
+}
+
+func (p *Pusher) RequestFinished(key tester.Key, sample tester.Sample) {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	w := p.window(key)
+	w.inFlight--
+	w.requests++
+
+	if sample.Err != nil {
+		w.errors++
+		return
+	}
+
+	w.statuses[sample.StatusCode]++
+	w.latency.Record(sample.Duration)
+}
+
+// window returns the window of the url, p.mx must be locked
+func (p *Pusher) window(key tester.Key) *window {
+	w, ok := p.windows[key]
+	if !ok {
+		w = newWindow()
+		p.windows[key] = w
+	}
+
+	return w
+}
+
+// flush pushes metrics of the current interval to all outputs and starts the next interval
+func (p *Pusher) flush() {
+	points := p.collect()
+	if len(points) == 0 {
+		return
+	}
+
+	for _, o := range p.outputs {
+		ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+
+		err := o.Write(ctx, points)
+		if err != nil {
+			p.log.WithError(err).WithField("output", o.Name()).Error("push metrics failed")
+		}
+
+		cancel()
+	}
+}
+
+func (p *Pusher) collect() []Point {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(p.last).Seconds()
+	p.last = now
+
+	points := make([]Point, 0, len(p.windows))
+
+	for key, w := range p.windows {
+		tags := []Tag{{Name: "host", Value: key.Host}, {Name: "url", Value: key.URL}}
+
+		var rps float64
+		if elapsed > 0 {
+			rps = float64(w.requests) / elapsed
+		}
+
+		points = append(points, Point{
+			Measurement: requestsMeasurement,
+			Tags:        tags,
+			Fields: []Field{
+				{Name: "requests", Value: float64(w.requests), Counter: true},
+				{Name: "errors", Value: float64(w.errors), Counter: true},
+				{Name: "rps", Value: rps},
+				{Name: "concurrency", Value: float64(w.concurrency)},
+				{Name: "in_flight", Value: float64(w.inFlight)},
+				{Name: "latency_mean", Value: w.latency.Mean().Seconds()},
+				{Name: "latency_min", Value: w.latency.Min.Seconds()},
+				{Name: "latency_max", Value: w.latency.Max.Seconds()},
+				{Name: "latency_p50", Value: w.latency.Quantile(0.5).Seconds()},
+				{Name: "latency_p90", Value: w.latency.Quantile(0.9).Seconds()},
+				{Name: "latency_p95", Value: w.latency.Quantile(0.95).Seconds()},
+				{Name: "latency_p99", Value: w.latency.Quantile(0.99).Seconds()},
+			},
+			Time: now,
+		})
+
+		statuses := make([]int, 0, len(w.statuses))
+		for status := range w.statuses {
+			statuses = append(statuses, status)
+		}
+
+		sort.Ints(statuses)
+
+		for _, status := range statuses {
+			points = append(points, Point{
+				Measurement: responsesMeasurement,
+				Tags:        append(tags, Tag{Name: "status", Value: strconv.Itoa(status)}),
+				Fields:      []Field{{Name: "count", Value: float64(w.statuses[status]), Counter: true}},
+				Time:        now,
+			})
+		}
+
+		next := newWindow()
+		next.concurrency = w.concurrency
+		next.inFlight = w.inFlight
+
+		p.windows[key] = next
+	}
+
+	return points
+}
+
+func (p *Pusher) closeOutputs() {
+	for _, o := range p.outputs {
+		err := o.Close()
+		if err != nil {
+			p.log.WithError(err).WithField("output", o.Name()).Error("close output failed")
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package outputs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// remoteWrite writes points with Prometheus remote write protocol,
+// every field is a separate series `measurement_field` with tags as labels,
+// counters are cumulative series `measurement_field_total`
+type remoteWrite struct {
+	url    string
+	client *http.Client
+	totals counters
+}
+
+func newRemoteWrite(cfg config.PrometheusRemoteWriteOutput) *remoteWrite {
+	return &remoteWrite{
+		url:    cfg.URL,
+		client: &http.Client{Timeout: writeTimeout},
+		totals: make(counters),
+	}
+}
+
+func (o *remoteWrite) Name() string {
+	return "prometheus_remote_write"
+}
+
+func (o *remoteWrite) Write(ctx context.Context, points []Point) error {
+	body := snappy.Encode(nil, encodeWriteRequest(points, o.totals))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write responded %d: %s", resp.StatusCode, body)
+	}
+
+	return nil
+}
+
+func (o *remoteWrite) Close() error {
+	return nil
+}
+
+// counters represents cumulative values of counter series by their names and labels,
+// counts of intervals are added to them so that rate() and increase() work
+type counters map[string]float64
+
+// add adds the count of the interval to the counter series and returns its total
+func (c counters) add(name string, labels []Tag, value float64) float64 {
+	key := strings.Builder{}
+	key.WriteString(name)
+
+	for _, label := range labels {
+		key.WriteString("\xff")
+		key.WriteString(label.Name)
+		key.WriteString("=")
+		key.WriteString(label.Value)
+	}
+
+	c[key.String()] += value
+
+	return c[key.String()]
+}
+
+// encodeWriteRequest encodes points to prometheus.WriteRequest protobuf message:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+//
+// counter fields are sent as cumulative totals of their series
+func encodeWriteRequest(points []Point, totals counters) []byte {
+	var req []byte
+
+	for _, p := range points {
+		timestamp := p.Time.UnixNano() / 1e6
+
+		// labels must be sorted by name, __name__ is the first
+		labels := append([]Tag(nil), p.Tags...)
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		for _, field := range p.Fields {
+			name := p.Measurement + "_" + field.Name
+			value := field.Value
+
+			if field.Counter {
+				name += "_total"
+				value = totals.add(name, labels, value)
+			}
+
+			var series []byte
+
+			series = appendLabel(series, "__name__", name)
+			for _, label := range labels {
+				series = appendLabel(series, label.Name, label.Value)
+			}
+
+			var sample []byte
+			sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+			sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+			sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+			sample = protowire.AppendVarint(sample, uint64(timestamp))
+
+			series = protowire.AppendTag(series, 2, protowire.BytesType)
+			series = protowire.AppendBytes(series, sample)
+
+			req = protowire.AppendTag(req, 1, protowire.BytesType)
+			req = protowire.AppendBytes(req, series)
+		}
+	}
+
+	return req
+}
+
+func appendLabel(b []byte, name, value string) []byte {
+	var label []byte
+	label = protowire.AppendTag(label, 1, protowire.BytesType)
+	label = protowire.AppendString(label, name)
+	label = protowire.AppendTag(label, 2, protowire.BytesType)
+	label = protowire.AppendString(label, value)
+
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+
+	return protowire.AppendBytes(b, label)
+}
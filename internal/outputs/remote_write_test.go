@@ -0,0 +1,165 @@
+package outputs
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// series represents the decoded time series of the write request
+type series struct {
+	labels    []Tag
+	value     float64
+	timestamp int64
+}
+
+// TestRemoteWrite writes points twice to the stand-in remote write receiver
+func TestRemoteWrite(t *testing.T) {
+	received := make(chan []series, 2)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		msg, err := snappy.Decode(nil, body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		received <- decodeWriteRequest(t, msg)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	o := newRemoteWrite(config.PrometheusRemoteWriteOutput{URL: receiver.URL})
+
+	now := time.Unix(1700000000, 0)
+	point := Point{
+		Measurement: responsesMeasurement,
+		Tags:        []Tag{{Name: "host", Value: "test.com"}, {Name: "url", Value: "http://test.com"}, {Name: "status", Value: "200"}},
+		Fields:      []Field{{Name: "count", Value: 3, Counter: true}, {Name: "rps", Value: 1.5}},
+		Time:        now,
+	}
+
+	for _, count := range []float64{3, 4} {
+		point.Fields[0].Value = count
+
+		err := o.Write(context.Background(), []Point{point})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, expected := range []float64{3, 7} {
+		got := <-received
+		if len(got) != 2 {
+			t.Fatalf("write %d: %d series, expected 2", i, len(got))
+		}
+
+		counter, gauge := got[0], got[1]
+
+		for _, s := range got {
+			if !sort.SliceIsSorted(s.labels, func(i, j int) bool { return s.labels[i].Name < s.labels[j].Name }) {
+				t.Fatalf("write %d: labels are not sorted: %v", i, s.labels)
+			}
+
+			if s.timestamp != now.UnixNano()/1e6 {
+				t.Fatalf("write %d: timestamp %d, expected %d", i, s.timestamp, now.UnixNano()/1e6)
+			}
+		}
+
+		if name := counter.labels[0]; name.Name != "__name__" || name.Value != "ldtester_responses_count_total" {
+			t.Fatalf("write %d: counter name %v", i, name)
+		}
+
+		if counter.value != expected {
+			t.Fatalf("write %d: counter %v, expected the cumulative %v", i, counter.value, expected)
+		}
+
+		if name := gauge.labels[0]; name.Value != "ldtester_responses_rps" || gauge.value != 1.5 {
+			t.Fatalf("write %d: gauge %v %v", i, name, gauge.value)
+		}
+	}
+}
+
+// decodeWriteRequest decodes time series of prometheus.WriteRequest message
+func decodeWriteRequest(t *testing.T, msg []byte) []series {
+	t.Helper()
+
+	var result []series
+
+	for _, ts := range decodeFields(t, msg) {
+		s := series{}
+
+		for _, field := range decodeFields(t, ts.value) {
+			switch field.num {
+			case 1:
+				label := decodeFields(t, field.value)
+				s.labels = append(s.labels, Tag{Name: string(label[0].value), Value: string(label[1].value)})
+			case 2:
+				sample := field.value
+
+				_, _, n := protowire.ConsumeTag(sample)
+				bits, m := protowire.ConsumeFixed64(sample[n:])
+				sample = sample[n+m:]
+
+				_, _, n = protowire.ConsumeTag(sample)
+				timestamp, _ := protowire.ConsumeVarint(sample[n:])
+
+				s.value, s.timestamp = math.Float64frombits(bits), int64(timestamp)
+			}
+		}
+
+		result = append(result, s)
+	}
+
+	return result
+}
+
+type bytesField struct {
+	num   protowire.Number
+	value []byte
+}
+
+// decodeFields decodes length-delimited fields of the message
+func decodeFields(t *testing.T, msg []byte) []bytesField {
+	t.Helper()
+
+	var fields []bytesField
+
+	for len(msg) > 0 {
+		num, typ, n := protowire.ConsumeTag(msg)
+		if n < 0 || typ != protowire.BytesType {
+			t.Fatalf("unexpected field %d of type %d", num, typ)
+		}
+
+		value, m := protowire.ConsumeBytes(msg[n:])
+		if m < 0 {
+			t.Fatalf("invalid field %d", num)
+		}
+
+		fields = append(fields, bytesField{num: num, value: value})
+		msg = msg[n+m:]
+	}
+
+	return fields
+}
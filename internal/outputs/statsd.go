@@ -0,0 +1,53 @@
+package outputs
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// statsD writes points over udp, counters as `path:value|c` and other fields as gauges `path:value|g`
+type statsD struct {
+	conn   net.Conn
+	prefix string
+}
+
+func newStatsD(cfg config.StatsDOutput) (*statsD, error) {
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsD{
+		conn:   conn,
+		prefix: cfg.Prefix,
+	}, nil
+}
+
+func (o *statsD) Name() string {
+	return "statsd"
+}
+
+func (o *statsD) Write(_ context.Context, points []Point) error {
+	lines := make([]string, 0, len(points))
+
+	for _, p := range points {
+		for _, field := range p.Fields {
+			metricType := "|g"
+			if field.Counter {
+				metricType = "|c"
+			}
+
+			lines = append(lines, metricPath(o.prefix, p, field)+":"+
+				strconv.FormatFloat(field.Value, 'f', -1, 64)+metricType)
+		}
+	}
+
+	return writePackets(o.conn, lines)
+}
+
+func (o *statsD) Close() error {
+	return o.conn.Close()
+}
@@ -0,0 +1,160 @@
+package proxyconf
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+	"github.com/tagirmukail/ldtester/internal/dialer"
+)
+
+// redacted replaces the password of the proxy url like url.URL.Redacted
+const redacted = "xxxxx"
+
+// Options represents the proxy of target connections, requests are sent directly if options are empty
+type Options struct {
+	URL             string `json:"url,omitempty"`              // http, https, socks5 or socks5h proxy, credentials are in the user info
+	FromEnvironment bool   `json:"from_environment,omitempty"` // HTTP_PROXY, HTTPS_PROXY and NO_PROXY are used if URL is empty
+}
+
+func FromConfig(cfg config.Proxy) Options {
+	return Options{
+		URL:             cfg.URL,
+		FromEnvironment: cfg.FromEnvironment,
+	}
+}
+
+// Override returns the proxy of the url if it is set
+func (o Options) Override(item *Options) Options {
+	if item == nil || item.URL == "" && !item.FromEnvironment {
+		return o
+	}
+
+	return *item
+}
+
+// Redacted returns options with the password of the proxy url replaced by "xxxxx", they are used in responses,
+// runs history and logs, the invalid url is replaced entirely
+func (o Options) Redacted() Options {
+	if o.URL == "" {
+		return o
+	}
+
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		o.URL = redacted
+		return o
+	}
+
+	o.URL = u.Redacted()
+
+	return o
+}
+
+// Validate checks the proxy url
+func (o Options) Validate() error {
+	_, err := o.parse()
+
+	return err
+}
+
+// HTTP returns true if requests are sent through the http proxy of the url or of the environment,
+// socks proxies dial connections of the transport instead
+func (o Options) HTTP() bool {
+	u, err := o.parse()
+	if err != nil {
+		return false
+	}
+
+	if u == nil {
+		return o.FromEnvironment
+	}
+
+	return u.Scheme == "http" || u.Scheme == "https"
+}
+
+// Tunneled returns true if connections of the request are established by the handshake with the proxy:
+// CONNECT of https requests through the http proxy or the socks handshake
+func (o Options) Tunneled(req *http.Request) bool {
+	u, err := o.parse()
+	if err != nil {
+		return false
+	}
+
+	if u == nil && o.FromEnvironment {
+		u, err = http.ProxyFromEnvironment(req)
+		if err != nil {
+			return false
+		}
+	}
+
+	if u == nil {
+		return false
+	}
+
+	return req.URL.Scheme == "https" || u.Scheme == "socks5" || u.Scheme == "socks5h"
+}
+
+// Apply sets the proxy of the transport, dial dials connections to the socks proxy
+func (o Options) Apply(tr *http.Transport, dial dialer.DialFunc) error {
+	u, err := o.parse()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case u == nil && o.FromEnvironment:
+		tr.Proxy = http.ProxyFromEnvironment
+	case u == nil:
+	case u.Scheme == "http" || u.Scheme == "https":
+		tr.Proxy = http.ProxyURL(u)
+	default:
+		d, err := proxy.FromURL(u, dial)
+		if err != nil {
+			return err
+		}
+
+		contextDialer, ok := d.(proxy.ContextDialer)
+		if !ok {
+			return fmt.Errorf("proxy %s doesn't support contexts", u.Redacted())
+		}
+
+		tr.DialContext = contextDialer.DialContext
+	}
+
+	return nil
+}
+
+// parse returns the proxy url, nil if it is not set
+func (o Options) parse() (*url.URL, error) {
+	if o.URL == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(o.URL)
+	if err != nil {
+		// the error of url.Parse contains the url with credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return nil, fmt.Errorf("invalid proxy url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unknown proxy scheme %q, expected one of: http, https, socks5, socks5h", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return nil, errors.New("proxy host is required")
+	}
+
+	return u, nil
+}
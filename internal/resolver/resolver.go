@@ -0,0 +1,286 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+	"github.com/tagirmukail/ldtester/internal/dialer"
+)
+
+// Preferences of ip versions
+const (
+	PreferIPv4 = "ipv4"
+	PreferIPv6 = "ipv6"
+)
+
+var errNoAddrs = errors.New("no addresses")
+
+// Options represents the resolving of target hosts, the system resolver is used if options are empty
+type Options struct {
+	Resolve    []string `json:"resolve,omitempty"`     // host:port:addr[,addr] mappings like curl --resolve
+	Server     string   `json:"server,omitempty"`      // dns server host:port, the system dns server if empty
+	Cache      bool     `json:"cache,omitempty"`       // addresses of hosts are resolved once
+	RoundRobin bool     `json:"round_robin,omitempty"` // connections are distributed across all addresses of the host
+	Prefer     string   `json:"prefer,omitempty"`      // ipv4 or ipv6 addresses first
+}
+
+func FromConfig(cfg config.DNS) Options {
+	return Options{
+		Resolve:    cfg.Resolve,
+		Server:     cfg.Server,
+		Cache:      cfg.Cache,
+		RoundRobin: cfg.RoundRobin,
+		Prefer:     cfg.Prefer,
+	}
+}
+
+// Empty returns true if the system resolver is used
+func (o Options) Empty() bool {
+	return len(o.Resolve) == 0 && o.Server == "" && !o.Cache && !o.RoundRobin && o.Prefer == ""
+}
+
+// Validate checks resolve mappings and the ip preference
+func (o Options) Validate() error {
+	_, err := New(o)
+
+	return err
+}
+
+// Resolver dials connections to addresses of hosts by options
+type Resolver struct {
+	opts Options
+
+	resolve map[string][]string // host:port to addresses
+	net     *net.Resolver
+	dialer  *net.Dialer
+
+	mx    sync.Mutex
+	cache map[string][]net.IP
+
+	next sync.Map // host to *uint64 counter of round-robin
+}
+
+func New(opts Options) (*Resolver, error) {
+	r := &Resolver{
+		opts:    opts,
+		resolve: make(map[string][]string, len(opts.Resolve)),
+		net:     net.DefaultResolver,
+		dialer:  dialer.New(nil),
+		cache:   make(map[string][]net.IP),
+	}
+
+	switch opts.Prefer {
+	case "", PreferIPv4, PreferIPv6:
+	default:
+		return nil, fmt.Errorf("unknown ip preference %q, expected ipv4 or ipv6", opts.Prefer)
+	}
+
+	for _, mapping := range opts.Resolve {
+		host, port, addrs, err := parseResolve(mapping)
+		if err != nil {
+			return nil, err
+		}
+
+		hostPort := net.JoinHostPort(host, port)
+
+		for _, addr := range addrs {
+			r.resolve[hostPort] = append(r.resolve[hostPort], net.JoinHostPort(addr, port))
+		}
+	}
+
+	if opts.Server != "" {
+		server := opts.Server
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+
+		r.net = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return r.dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return r, nil
+}
+
+// parseResolve parses the mapping host:port:addr[,addr] like curl --resolve, ipv6 hosts and addresses are
+// in brackets [::1], unbracketed ipv6 addresses are accepted after the port
+func parseResolve(mapping string) (string, string, []string, error) {
+	invalid := fmt.Errorf("invalid resolve %q, expected host:port:addr[,addr]", mapping)
+
+	host, rest := mapping, ""
+
+	if strings.HasPrefix(mapping, "[") {
+		end := strings.Index(mapping, "]:")
+		if end < 0 {
+			return "", "", nil, invalid
+		}
+
+		host, rest = mapping[1:end], mapping[end+2:]
+	} else {
+		i := strings.Index(mapping, ":")
+		if i < 0 {
+			return "", "", nil, invalid
+		}
+
+		host, rest = mapping[:i], mapping[i+1:]
+	}
+
+	i := strings.Index(rest, ":")
+	if host == "" || i <= 0 || i == len(rest)-1 {
+		return "", "", nil, invalid
+	}
+
+	port := rest[:i]
+
+	addrs := strings.Split(rest[i+1:], ",")
+	for n, addr := range addrs {
+		addrs[n] = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if net.ParseIP(addrs[n]) == nil {
+			return "", "", nil, fmt.Errorf("invalid address %q of resolve %q", addr, mapping)
+		}
+	}
+
+	return host, port, addrs, nil
+}
+
+// DialContext dials the connection to the address of the host, addresses are tried in order until the first success
+func (r *Resolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return r.dial(ctx, network, addr, r.dialer.DialContext)
+}
+
+// Dial returns the dial func that resolves addresses of hosts and dials them by dial
+func (r *Resolver) Dial(dial dialer.DialFunc) dialer.DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return r.dial(ctx, network, addr, dial)
+	}
+}
+
+func (r *Resolver) dial(ctx context.Context, network, addr string, dial dialer.DialFunc) (net.Conn, error) {
+	addrs, err := r.addrs(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if r.opts.RoundRobin && len(addrs) > 1 {
+		counter, _ := r.next.LoadOrStore(addr, new(uint64))
+		start = int((atomic.AddUint64(counter.(*uint64), 1) - 1) % uint64(len(addrs)))
+	}
+
+	var conn net.Conn
+	for i := range addrs {
+		conn, err = dial(ctx, network, addrs[(start+i)%len(addrs)])
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// addrs returns addresses host:port of the address of the url
+func (r *Resolver) addrs(ctx context.Context, addr string) ([]string, error) {
+	if addrs, ok := r.resolve[addr]; ok {
+		return addrs, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return []string{addr}, nil
+	}
+
+	ips, err := r.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		result = append(result, net.JoinHostPort(ip.String(), port))
+	}
+
+	return result, nil
+}
+
+// lookup returns ip addresses of the host in order of the preference
+func (r *Resolver) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if r.opts.Cache {
+		r.mx.Lock()
+		ips, ok := r.cache[host]
+		r.mx.Unlock()
+
+		if ok {
+			return ips, nil
+		}
+	}
+
+	// the lookup is reported as the dns phase of the request, connections to the dns server are not traced
+	// as connections of the request
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+
+	ipAddrs, err := r.net.LookupIPAddr(untraced{ctx}, host)
+
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: ipAddrs, Err: err})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ipAddrs) == 0 {
+		return nil, fmt.Errorf("%w of %s", errNoAddrs, host)
+	}
+
+	ips := make([]net.IP, 0, len(ipAddrs))
+	for _, ipAddr := range ipAddrs {
+		ips = append(ips, ipAddr.IP)
+	}
+
+	if r.opts.Prefer != "" {
+		sort.SliceStable(ips, func(i, j int) bool {
+			return r.preferred(ips[i]) && !r.preferred(ips[j])
+		})
+	}
+
+	if r.opts.Cache {
+		r.mx.Lock()
+		r.cache[host] = ips
+		r.mx.Unlock()
+	}
+
+	return ips, nil
+}
+
+// untraced is the context without values, so the trace of the request doesn't get events of the lookup
+type untraced struct {
+	context.Context
+}
+
+func (untraced) Value(interface{}) interface{} {
+	return nil
+}
+
+func (r *Resolver) preferred(ip net.IP) bool {
+	isIPv4 := ip.To4() != nil
+
+	return isIPv4 == (r.opts.Prefer == PreferIPv4)
+}
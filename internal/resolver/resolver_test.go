@@ -0,0 +1,151 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TestParseResolve checks mappings of resolve with ipv4 and ipv6 hosts and addresses
+func TestParseResolve(t *testing.T) {
+	cases := []struct {
+		mapping string
+		host    string
+		port    string
+		addrs   []string
+		invalid bool
+	}{
+		{mapping: "test.com:443:127.0.0.1", host: "test.com", port: "443", addrs: []string{"127.0.0.1"}},
+		{mapping: "test.com:443:[2001:db8::1],127.0.0.2", host: "test.com", port: "443", addrs: []string{"2001:db8::1", "127.0.0.2"}},
+		{mapping: "test.com:443:2001:db8::1", host: "test.com", port: "443", addrs: []string{"2001:db8::1"}},
+		{mapping: "[::1]:8080:[::2]", host: "::1", port: "8080", addrs: []string{"::2"}},
+		{mapping: "test.com:443", invalid: true},
+		{mapping: "test.com:443:", invalid: true},
+		{mapping: "test.com::127.0.0.1", invalid: true},
+		{mapping: ":443:127.0.0.1", invalid: true},
+		{mapping: "[::1:443:127.0.0.1", invalid: true},
+		{mapping: "test.com:443:test.org", invalid: true},
+	}
+
+	for _, c := range cases {
+		host, port, addrs, err := parseResolve(c.mapping)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%s: expected the error", c.mapping)
+			}
+
+			continue
+		}
+
+		if err != nil || host != c.host || port != c.port || !reflect.DeepEqual(addrs, c.addrs) {
+			t.Errorf("%s: %s %s %v %v, expected %s %s %v", c.mapping, host, port, addrs, err, c.host, c.port, c.addrs)
+		}
+	}
+}
+
+// TestLookupTrace checks that the lookup by the dns server is traced as dns and the connection to the dns server
+// isn't traced as the connection of the request
+func TestLookupTrace(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer target.Close()
+
+	_, port, err := net.SplitHostPort(target.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dns := serveDNS(t, net.IPv4(127, 0, 0, 1))
+
+	r, err := New(Options{Server: dns, Prefer: PreferIPv4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dnsStarts, dnsDones int
+	var connects []string
+
+	ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { dnsStarts++ },
+		DNSDone:      func(httptrace.DNSDoneInfo) { dnsDones++ },
+		ConnectStart: func(_, addr string) { connects = append(connects, addr) },
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://target.test:"+port, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&http.Transport{DialContext: r.DialContext}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if dnsStarts != 1 || dnsDones != 1 {
+		t.Fatalf("dns start %d, done %d, expected one lookup", dnsStarts, dnsDones)
+	}
+
+	if expected := []string{"127.0.0.1:" + port}; !reflect.DeepEqual(connects, expected) {
+		t.Fatalf("connects %v, expected %v", connects, expected)
+	}
+}
+
+// serveDNS serves A records with the ip for all names over udp, returns the address of the server
+func serveDNS(t *testing.T, ip net.IP) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			var query dnsmessage.Message
+			if query.Unpack(buf[:n]) != nil || len(query.Questions) == 0 {
+				continue
+			}
+
+			question := query.Questions[0]
+
+			answer := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+
+			if question.Type == dnsmessage.TypeA {
+				var a dnsmessage.AResource
+				copy(a.A[:], ip.To4())
+
+				answer.Answers = append(answer.Answers, dnsmessage.Resource{
+					Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &a,
+				})
+			}
+
+			packed, err := answer.Pack()
+			if err != nil {
+				continue
+			}
+
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
@@ -0,0 +1,213 @@
+package router
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/tagirmukail/ldtester/internal/cluster"
+	"github.com/tagirmukail/ldtester/internal/store"
+	"github.com/tagirmukail/ldtester/internal/tester"
+)
+
+// clusterLoadHandler starts the load test on registered agents and returns the id of its job,
+// the merged report is returned by GET /cluster/jobs/{id}
+func (r *Router) clusterLoadHandler(w http.ResponseWriter, req *http.Request) {
+	items, err := decodeItems(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	agentsCount, err := r.testerConfSetParamInt(agentsCountHeader, agentsCountParam, req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	conf, err := r.testerConfiguration(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	err = tester.ValidateTransport(conf, items)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	if !r.acquireJob(req) {
+		return
+	}
+
+	label := r.testerConfReqString(runLabelHeader, runLabelParam, req)
+	startedAt := time.Now()
+
+	// the job outlives the request, the load test lasts longer than the write timeout of the server
+	id, err := r.options.Cluster.Start(conf, items, agentsCount,
+		time.Duration(r.options.Cfg.StressTestTimeout)*time.Second, func(result cluster.JobResult) {
+			defer r.releaseJob()
+
+			r.saveRun(&store.Run{
+				Label:      label,
+				StartedAt:  startedAt,
+				FinishedAt: time.Now(),
+				Config:     conf,
+				Items:      items,
+				Report:     result.Report,
+				Verdicts:   result.Verdicts,
+			})
+		})
+	switch {
+	case errors.Is(err, cluster.ErrNotEnoughAgents):
+		r.releaseJob()
+		r.json(w, http.StatusConflict, &response{Message: err.Error()})
+		return
+	case err != nil:
+		r.releaseJob()
+		r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+		return
+	}
+
+	redactedConf := conf.Redacted()
+
+	r.json(w, http.StatusAccepted, &response{
+		Message:        "accepted",
+		LoadTestConfig: &redactedConf,
+		Data:           &cluster.StartResponse{ID: id},
+	})
+}
+
+// clusterJobHandler returns the merged current report of the running job or the merged report of the finished job
+func (r *Router) clusterJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	group, err := r.groupBy(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	result, err := r.options.Cluster.Job(id)
+	if err != nil {
+		r.json(w, http.StatusNotFound, &response{Message: err.Error()})
+		return
+	}
+
+	result.Report = tester.GroupBy(tester.ReportKeys(result.Report), group)
+
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: result})
+}
+
+// clusterJobsHandler returns merged current reports of running distributed load tests
+func (r *Router) clusterJobsHandler(w http.ResponseWriter, _ *http.Request) {
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: r.options.Cluster.Jobs()})
+}
+
+// clusterAgentsHandler returns registered agents
+func (r *Router) clusterAgentsHandler(w http.ResponseWriter, _ *http.Request) {
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: r.options.Cluster.Agents()})
+}
+
+// agentAuth checks the shared token of agents if it is set, jobs contain credentials of targets
+func (r *Router) agentAuth(next http.HandlerFunc) http.HandlerFunc {
+	token := []byte(r.options.Cfg.Cluster.Token)
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		if len(token) > 0 && subtle.ConstantTimeCompare([]byte(req.Header.Get(cluster.TokenHeader)), token) != 1 {
+			r.json(w, http.StatusUnauthorized, &response{Message: "invalid agent token"})
+			return
+		}
+
+		next(w, req)
+	}
+}
+
+// registerAgentHandler registers the agent and returns its id
+func (r *Router) registerAgentHandler(w http.ResponseWriter, req *http.Request) {
+	registration := &cluster.RegisterRequest{}
+
+	err := jsoniter.NewDecoder(req.Body).Decode(registration)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	id := r.options.Cluster.Register(registration.Name)
+
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: &cluster.RegisterResponse{ID: id}})
+}
+
+// agentJobHandler waits for the job of the agent, returns no content if there is no job
+func (r *Router) agentJobHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	job, err := r.options.Cluster.NextJob(req.Context(), id)
+	switch {
+	case errors.Is(err, cluster.ErrUnknownAgent):
+		r.json(w, http.StatusNotFound, &response{Message: err.Error()})
+		return
+	case err != nil:
+		r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+		return
+	case job == nil:
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set(contentTypeHeader, contentTypeJson)
+	w.WriteHeader(http.StatusOK)
+
+	// the agent polls the job again if it didn't receive the job
+	err = jsoniter.NewEncoder(w).Encode(&response{Message: "successfully", Data: job})
+	if err != nil {
+		r.options.Log.WithError(err).WithField("job_id", job.ID).Error("write the job to the agent failed")
+		r.options.Cluster.Requeue(id, job)
+	}
+}
+
+// agentReportHandler saves the current or the final report of the agent job
+func (r *Router) agentReportHandler(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	report := &cluster.AgentReport{}
+
+	err = jsoniter.NewDecoder(req.Body).Decode(report)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	err = r.options.Cluster.Report(id, report)
+	switch {
+	case errors.Is(err, cluster.ErrUnknownAgent):
+		r.json(w, http.StatusNotFound, &response{Message: err.Error()})
+		return
+	case errors.Is(err, cluster.ErrUnknownJob):
+		r.json(w, http.StatusConflict, &response{Message: err.Error()})
+		return
+	case err != nil:
+		r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+		return
+	}
+
+	r.json(w, http.StatusOK, &response{Message: "successfully"})
+}
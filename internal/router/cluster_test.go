@@ -0,0 +1,142 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/cluster"
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+const testToken = "secret"
+
+// TestClusterLoad runs the distributed load test on the coordinator and two agents on localhost
+func TestClusterLoad(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+
+	cfg := config.DefaultConfig()
+	cfg.StressTestTimeout = 2
+	cfg.Cluster.Token = testToken
+
+	r := New(&Options{
+		Cfg:     &cfg,
+		Log:     log,
+		Cluster: cluster.NewCoordinator(log, 200*time.Millisecond),
+	})
+
+	coordinator := httptest.NewServer(r.router())
+	defer coordinator.Close()
+
+	resp, err := http.Post(coordinator.URL+"/cluster/agents", "application/json", strings.NewReader(`{"name":"intruder"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("registration without the token: status %d, expected %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, name := range []string{"agent1", "agent2"} {
+		go func(name string) {
+			_ = cluster.NewAgent(coordinator.URL, name, testToken, log).Run(ctx)
+		}(name)
+	}
+
+	waitFor(t, func() bool { return len(r.options.Cluster.Agents()) == 2 })
+
+	body := fmt.Sprintf(`[{"url": %q}]`, target.URL)
+
+	started := &struct {
+		Data cluster.StartResponse `json:"data"`
+	}{}
+
+	status := doJSON(t, http.MethodPost, coordinator.URL+"/cluster/load", body, started)
+	if status != http.StatusAccepted {
+		t.Fatalf("start the load test: status %d, expected %d", status, http.StatusAccepted)
+	}
+
+	result := &struct {
+		Data cluster.JobResult `json:"data"`
+	}{}
+
+	waitFor(t, func() bool {
+		doJSON(t, http.MethodGet, fmt.Sprintf("%s/cluster/jobs/%d", coordinator.URL, started.Data.ID), "", result)
+
+		return result.Data.Done
+	})
+
+	if len(result.Data.Agents) != 2 {
+		t.Fatalf("agents of the job %d, expected 2", len(result.Data.Agents))
+	}
+
+	total := 0
+
+	for _, agent := range result.Data.Agents {
+		item := agent.Report[target.URL]
+		if !agent.Done || item.TotalReqCount == 0 {
+			t.Fatalf("agent %s: done %t, requests %d", agent.Name, agent.Done, item.TotalReqCount)
+		}
+
+		total += item.TotalReqCount
+	}
+
+	if merged := result.Data.Report[target.URL].TotalReqCount; merged != total {
+		t.Fatalf("merged requests %d, expected the sum of agents %d", merged, total)
+	}
+}
+
+// doJSON sends the request and decodes the response into out, returns the status code
+func doJSON(t *testing.T, method, url, body string, out interface{}) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	err = jsoniter.NewDecoder(resp.Body).Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode
+}
+
+// waitFor waits until cond is true for 30 seconds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in 30 seconds")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -9,6 +9,43 @@ const (
 	reqMethodHeader          = "T-Method"
 	reqAcceptHeader          = "T-Accept"
 	reqUserAgentHeader       = "T-User-Agent"
+	roundIntervalHeader      = "T-Round-Interval"
+	warmUpHeader             = "T-Warm-Up"
+	warmUpRequestsHeader     = "T-Warm-Up-Requests"
+	coolDownHeader           = "T-Cool-Down"
+	thinkTimeHeader          = "T-Think-Time"
+	pacingHeader             = "T-Pacing"
+	mixHeader                = "T-Mix"
+	hostBudgetHeader         = "T-Host-Budget"
+	rateLimitModeHeader      = "T-Rate-Limit-Mode"
+	rateLimitRetriesHeader   = "T-Rate-Limit-Retries"
+	tlsVerifyHeader          = "T-TLS-Verify"
+	tlsMinVersionHeader      = "T-TLS-Min-Version"
+	tlsMaxVersionHeader      = "T-TLS-Max-Version"
+	tlsServerNameHeader      = "T-TLS-Server-Name"
+	resolveHeader            = "T-Resolve"
+	dnsServerHeader          = "T-DNS-Server"
+	dnsCacheHeader           = "T-DNS-Cache"
+	dnsRoundRobinHeader      = "T-DNS-Round-Robin"
+	dnsPreferHeader          = "T-DNS-Prefer"
+	proxyHeader              = "T-Proxy"
+	proxyFromEnvHeader       = "T-Proxy-From-Env"
+	localAddrHeader          = "T-Local-Addr"
+	localAddrModeHeader      = "T-Local-Addr-Mode"
+	unixSocketHeader         = "T-Unix-Socket"
+	http2Header              = "T-HTTP2"
+	h2cHeader                = "T-H2C"
+	h2MaxInFlightHeader      = "T-H2-Max-In-Flight"
+	h2ConnectionsHeader      = "T-H2-Connections"
+	cookiesHeader            = "T-Cookies"
+	cookiesSharedHeader      = "T-Cookies-Shared"
+	cookiesClearHeader       = "T-Cookies-Clear"
+	redirectsHeader          = "T-Redirects"
+	redirectMaxHopsHeader    = "T-Redirect-Max-Hops"
+	responseBodyHeader       = "T-Response-Body"
+	runLabelHeader           = "T-Run-Label"
+	agentsCountHeader        = "T-Agents"
+	groupByHeader            = "T-Group-By"
 
 	// Query Params Names
 	maxIdleConnPerHostParam = "tmaxidleconnhost"
@@ -16,4 +53,44 @@ const (
 	disableKeepAliveParam   = "tdisablekeepalive"
 	reqTimeoutParam         = "treqtimeout"
 	reqMethodParam          = "tmethod"
+	roundIntervalParam      = "troundinterval"
+	warmUpParam             = "twarmup"
+	warmUpRequestsParam     = "twarmupreqs"
+	coolDownParam           = "tcooldown"
+	thinkTimeParam          = "tthinktime"
+	pacingParam             = "tpacing"
+	mixParam                = "tmix"
+	hostBudgetParam         = "thostbudget"
+	rateLimitModeParam      = "tratelimit"
+	rateLimitRetriesParam   = "tratelimitretries"
+	tlsVerifyParam          = "ttlsverify"
+	tlsMinVersionParam      = "ttlsminversion"
+	tlsMaxVersionParam      = "ttlsmaxversion"
+	tlsServerNameParam      = "ttlsservername"
+	resolveParam            = "tresolve"
+	dnsServerParam          = "tdnsserver"
+	dnsCacheParam           = "tdnscache"
+	dnsRoundRobinParam      = "tdnsroundrobin"
+	dnsPreferParam          = "tdnsprefer"
+	proxyParam              = "tproxy"
+	proxyFromEnvParam       = "tproxyenv"
+	localAddrParam          = "tlocaladdr"
+	localAddrModeParam      = "tlocaladdrmode"
+	unixSocketParam         = "tunixsocket"
+	http2Param              = "thttp2"
+	h2cParam                = "th2c"
+	h2MaxInFlightParam      = "th2maxinflight"
+	h2ConnectionsParam      = "th2conns"
+	cookiesParam            = "tcookies"
+	cookiesSharedParam      = "tcookiesshared"
+	cookiesClearParam       = "tcookiesclear"
+	redirectsParam          = "tredirects"
+	redirectMaxHopsParam    = "tredirectmaxhops"
+	responseBodyParam       = "tresponsebody"
+	runLabelParam           = "tlabel"
+	agentsCountParam        = "tagents"
+	groupByParam            = "group_by"
+	runIDsParam             = "ids"
+	runsLimitParam          = "limit"
+	trendURLParam           = "url"
 )
@@ -4,11 +4,21 @@ import (
 	"context"
 	"crypto/sha256"
 	"net/http"
+	nurl "net/url"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/tagirmukail/ldtester/internal/auth"
+	"github.com/tagirmukail/ldtester/internal/dialer"
+	"github.com/tagirmukail/ldtester/internal/h2conf"
+	"github.com/tagirmukail/ldtester/internal/outputs"
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
+	"github.com/tagirmukail/ldtester/internal/resolver"
+	"github.com/tagirmukail/ldtester/internal/selfmon"
+	"github.com/tagirmukail/ldtester/internal/store"
 	"github.com/tagirmukail/ldtester/internal/tester"
+	"github.com/tagirmukail/ldtester/internal/tlsconf"
 	"github.com/tagirmukail/ldtester/internal/url_item"
 )
 
@@ -18,22 +28,46 @@ const (
 
 // loadHandler handles urls with load testing every url and returns report {"url": {data}}
 func (r *Router) loadHandler(w http.ResponseWriter, req *http.Request) {
-	resp := &response{}
+	items, err := decodeItems(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
 
-	items := make([]url_item.Item, 0)
-	err := jsoniter.NewDecoder(req.Body).Decode(items)
+	group, err := r.groupBy(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	conf, err := r.testerConfiguration(req)
 	if err != nil {
-		resp.Message = err.Error()
-		r.json(w, http.StatusBadRequest, resp)
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	err = tester.ValidateTransport(conf, items)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
 		return
 	}
 
-	conf := r.testerConfiguration(req)
 	b, _ := jsoniter.Marshal(conf)
 	confHashSum := sha256.Sum256(b)
 	confHash := string(confHashSum[:])
 
-	result := r.getFromCache(confHash, items)
+	// urls of the mix and of host budgets are tested together, so the cache of single urls is not used
+	result := &getFromCacheResult{notFoundItems: items, report: map[tester.Key]tester.Item{}}
+	if !conf.Mix && !conf.HostBudget {
+		result = r.getFromCache(confHash, items)
+	}
+
+	if !r.acquireJob(req) {
+		return
+	}
+	defer r.releaseJob()
+
+	startedAt := time.Now()
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.options.Cfg.StressTestTimeout)*time.Second)
 	ctx = context.WithValue(ctx, tester.IsHandlerKey, true)
@@ -41,6 +75,25 @@ func (r *Router) loadHandler(w http.ResponseWriter, req *http.Request) {
 	t := tester.New(ctx, cancel, r.options.Log, conf, result.notFoundItems)
 	defer t.Stop()
 
+	t.AddObserver(r.options.Metrics)
+	t.SetTracer(r.options.Tracer)
+
+	if r.options.Cfg.SelfMonitor.Enabled {
+		t.SetMonitor(selfmon.New(selfmon.FromConfig(r.options.Cfg.SelfMonitor), r.options.Log))
+	}
+
+	if r.options.Cfg.Outputs.Enabled() {
+		pusher, err := outputs.New(r.options.Cfg.Outputs, r.options.Log)
+		if err != nil {
+			r.options.Log.WithError(err).Error("setup metrics outputs failed")
+		} else {
+			t.AddObserver(pusher)
+
+			pusher.Start()
+			defer pusher.Stop()
+		}
+	}
+
 	t.Run()
 
 	report := t.Report()
@@ -51,17 +104,63 @@ func (r *Router) loadHandler(w http.ResponseWriter, req *http.Request) {
 	}
 
 	resultResp := make(map[string]tester.Item)
-	for k, item := range report {
+	for k, item := range result.report {
 		resultResp[k.URL] = item
 	}
 
+	r.saveRun(&store.Run{
+		Label:      r.testerConfReqString(runLabelHeader, runLabelParam, req),
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		Config:     conf,
+		Items:      items,
+		Report:     resultResp,
+		Generator:  t.GeneratorReport(),
+		Verdicts:   t.Verdicts(),
+	})
+
+	var mix map[string]tester.MixItem
+	if conf.Mix {
+		mix = tester.MixReport(items, result.report)
+	}
+
+	redactedConf := conf.Redacted()
+
 	r.json(w, http.StatusOK, &response{
 		Message:        "successfully",
-		LoadTestConfig: conf,
-		Data:           resultResp,
+		LoadTestConfig: &redactedConf,
+		Data:           tester.GroupBy(result.report, group),
+		Generator:      t.GeneratorReport(),
+		Mix:            mix,
+		Verdicts:       t.Verdicts(),
 	})
 }
 
+// decodeItems decodes urls of the load test from the request body
+func decodeItems(req *http.Request) ([]url_item.Item, error) {
+	items := make([]url_item.Item, 0)
+	err := jsoniter.NewDecoder(req.Body).Decode(&items)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		parsedURL, err := nurl.Parse(items[i].Url)
+		if err != nil {
+			return nil, err
+		}
+
+		items[i].Host = parsedURL.Hostname()
+	}
+
+	err = tester.ValidateWeights(items)
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
 type getFromCacheResult struct {
 	notFoundItems []url_item.Item
 	report        map[tester.Key]tester.Item
@@ -98,7 +197,7 @@ func (r *Router) getFromCache(confHash string, items []url_item.Item) *getFromCa
 }
 
 // testerConfiguration sets tester configuration from request headers and url query params
-func (r *Router) testerConfiguration(req *http.Request) tester.Configuration {
+func (r *Router) testerConfiguration(req *http.Request) (tester.Configuration, error) {
 	tConf := tester.DefaultConfiguration()
 
 	if r.options.Cfg.LoadTest.MaxIdleConnPerHost > 0 {
@@ -133,7 +232,54 @@ func (r *Router) testerConfiguration(req *http.Request) tester.Configuration {
 		tConf.UserAgent = r.options.Cfg.LoadTest.UserAgent
 	}
 
-	tConf = r.testerConfFromReq(tConf, req)
+	if r.options.Cfg.LoadTest.RoundInterval > 0 {
+		tConf.RoundInterval = time.Duration(r.options.Cfg.LoadTest.RoundInterval) * time.Millisecond
+	}
+
+	if r.options.Cfg.LoadTest.WarmUp > 0 {
+		tConf.WarmUp = time.Duration(r.options.Cfg.LoadTest.WarmUp) * time.Second
+	}
+
+	if r.options.Cfg.LoadTest.WarmUpRequests > 0 {
+		tConf.WarmUpRequests = r.options.Cfg.LoadTest.WarmUpRequests
+	}
+
+	if r.options.Cfg.LoadTest.CoolDown > 0 {
+		tConf.CoolDown = time.Duration(r.options.Cfg.LoadTest.CoolDown) * time.Second
+	}
+
+	if r.options.Cfg.LoadTest.Mix {
+		tConf.Mix = true
+	}
+
+	if r.options.Cfg.LoadTest.HostBudget {
+		tConf.HostBudget = true
+	}
+
+	if r.options.Cfg.LoadTest.Pacing > 0 {
+		tConf.Pacing = time.Duration(r.options.Cfg.LoadTest.Pacing) * time.Millisecond
+	}
+
+	if r.options.Cfg.LoadTest.ResponseBody != "" {
+		tConf.ResponseBody = r.options.Cfg.LoadTest.ResponseBody
+	}
+
+	thinkTime, err := tester.ThinkTimeFromConfig(r.options.Cfg.LoadTest)
+	if err != nil {
+		return tConf, err
+	}
+
+	tConf.ThinkTime = thinkTime
+
+	tConf.RateLimit = tester.RateLimitFromConfig(tConf.RateLimit, r.options.Cfg.LoadTest)
+	tConf.TLS = tlsconf.FromConfig(r.options.Cfg.LoadTest.TLS)
+	tConf.Resolver = resolver.FromConfig(r.options.Cfg.LoadTest.DNS)
+	tConf.Proxy = proxyconf.FromConfig(r.options.Cfg.LoadTest.Proxy)
+	tConf.Dial = dialer.FromConfig(r.options.Cfg.LoadTest.Dial)
+	tConf.HTTP2 = h2conf.FromConfig(r.options.Cfg.LoadTest.HTTP2)
+	tConf.Auth = auth.FromConfig(r.options.Cfg.LoadTest.Auth)
+	tConf.Cookies = tester.CookiesFromConfig(r.options.Cfg.LoadTest.Cookies)
+	tConf.Redirects = tester.RedirectsFromConfig(r.options.Cfg.LoadTest.Redirects)
 
-	return tConf
+	return r.testerConfFromReq(tConf, req)
 }
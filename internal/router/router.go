@@ -9,8 +9,12 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/tagirmukail/ldtester/internal/cache"
+	"github.com/tagirmukail/ldtester/internal/cluster"
 	"github.com/tagirmukail/ldtester/internal/config"
 	"github.com/tagirmukail/ldtester/internal/logger"
+	"github.com/tagirmukail/ldtester/internal/metrics"
+	"github.com/tagirmukail/ldtester/internal/store"
+	"github.com/tagirmukail/ldtester/internal/tracing"
 )
 
 const timeout = 10 * time.Second
@@ -20,10 +24,16 @@ type Options struct {
 	Log     logger.Logger
 	HTTPCli *http.Client
 	Cache   *cache.Cache
+	Store   *store.Store // if nil, runs history is disabled
+	Metrics *metrics.Metrics
+	Tracer  *tracing.Tracer // if nil, tracing is disabled
+	Cluster *cluster.Coordinator
 }
 
 type Router struct {
 	options *Options
+
+	jobs chan struct{} // limits running load tests, nil if unlimited
 }
 
 func New(opts *Options) *Router {
@@ -49,17 +59,71 @@ func New(opts *Options) *Router {
 		opts.Cache = cache.New()
 	}
 
-	return &Router{options: opts}
+	if opts.Metrics == nil {
+		opts.Metrics = metrics.New(opts.Cache)
+	}
+
+	if opts.Cluster == nil {
+		opts.Cluster = cluster.NewCoordinator(opts.Log, time.Duration(opts.Cfg.Cluster.StartDelay)*time.Second)
+	}
+
+	r := &Router{options: opts}
+
+	if opts.Cfg.MaxRunningTests > 0 {
+		r.jobs = make(chan struct{}, opts.Cfg.MaxRunningTests)
+	}
+
+	return r
 }
 
 func (r *Router) router() *mux.Router {
 	router := mux.NewRouter()
 
+	router.Use(r.options.Metrics.Middleware)
+
+	router.Handle("/metrics", r.options.Metrics.Handler()).Methods(http.MethodGet)
 	router.HandleFunc("/load", r.loadHandler).Methods(http.MethodPost)
+	router.HandleFunc("/runs", r.runsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/runs/merged", r.mergedRunsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/runs/{id:[0-9]+}", r.runHandler).Methods(http.MethodGet)
+	router.HandleFunc("/trends", r.trendsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/load", r.clusterLoadHandler).Methods(http.MethodPost)
+	router.HandleFunc("/cluster/jobs", r.clusterJobsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/jobs/{id:[0-9]+}", r.clusterJobHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/agents", r.clusterAgentsHandler).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/agents", r.agentAuth(r.registerAgentHandler)).Methods(http.MethodPost)
+	router.HandleFunc("/cluster/agents/{id:[0-9]+}/job", r.agentAuth(r.agentJobHandler)).Methods(http.MethodGet)
+	router.HandleFunc("/cluster/agents/{id:[0-9]+}/report", r.agentAuth(r.agentReportHandler)).Methods(http.MethodPost)
 
 	return router
 }
 
+// acquireJob waits for a free slot for the load test, returns false if the request is canceled
+func (r *Router) acquireJob(req *http.Request) bool {
+	r.options.Metrics.JobQueued()
+
+	if r.jobs != nil {
+		select {
+		case r.jobs <- struct{}{}:
+		case <-req.Context().Done():
+			r.options.Metrics.JobCanceled()
+			return false
+		}
+	}
+
+	r.options.Metrics.JobStarted()
+
+	return true
+}
+
+func (r *Router) releaseJob() {
+	if r.jobs != nil {
+		<-r.jobs
+	}
+
+	r.options.Metrics.JobFinished()
+}
+
 func (r *Router) Serve() error {
 	srv := &http.Server{
 		Handler:      r.router(),
@@ -0,0 +1,146 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/tagirmukail/ldtester/internal/store"
+	"github.com/tagirmukail/ldtester/internal/tester"
+)
+
+const errRunsHistoryDisabled = "runs history is disabled"
+
+// saveRun saves the run to the runs history if it is enabled
+func (r *Router) saveRun(run *store.Run) {
+	if r.options.Store == nil {
+		return
+	}
+
+	err := r.options.Store.Save(run)
+	if err != nil {
+		r.options.Log.WithError(err).Error("save run failed")
+	}
+}
+
+// runsHandler returns the latest runs, newest first
+func (r *Router) runsHandler(w http.ResponseWriter, req *http.Request) {
+	if r.options.Store == nil {
+		r.json(w, http.StatusNotFound, &response{Message: errRunsHistoryDisabled})
+		return
+	}
+
+	limit, err := r.testerConfSetParamInt("", runsLimitParam, req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	runs, err := r.options.Store.List(limit)
+	if err != nil {
+		r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+		return
+	}
+
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: runs})
+}
+
+// runHandler returns the run with its configuration, items and report
+func (r *Router) runHandler(w http.ResponseWriter, req *http.Request) {
+	if r.options.Store == nil {
+		r.json(w, http.StatusNotFound, &response{Message: errRunsHistoryDisabled})
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(req)["id"], 10, 64)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	group, err := r.groupBy(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	run, err := r.options.Store.Get(id)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		r.json(w, http.StatusNotFound, &response{Message: err.Error()})
+		return
+	case err != nil:
+		r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+		return
+	}
+
+	run.Report = tester.GroupBy(tester.ReportKeys(run.Report), group)
+
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: run})
+}
+
+// mergedRunsHandler returns the merged report of repeated runs ?ids=1,2,3
+func (r *Router) mergedRunsHandler(w http.ResponseWriter, req *http.Request) {
+	if r.options.Store == nil {
+		r.json(w, http.StatusNotFound, &response{Message: errRunsHistoryDisabled})
+		return
+	}
+
+	group, err := r.groupBy(req)
+	if err != nil {
+		r.json(w, http.StatusBadRequest, &response{Message: err.Error()})
+		return
+	}
+
+	ids := strings.Split(req.URL.Query().Get(runIDsParam), ",")
+	reports := make([]map[string]tester.Item, 0, len(ids))
+
+	for _, rawID := range ids {
+		id, err := strconv.ParseUint(strings.TrimSpace(rawID), 10, 64)
+		if err != nil {
+			r.json(w, http.StatusBadRequest, &response{Message: "invalid run id " + rawID})
+			return
+		}
+
+		run, err := r.options.Store.Get(id)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			r.json(w, http.StatusNotFound, &response{Message: err.Error()})
+			return
+		case err != nil:
+			r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+			return
+		}
+
+		reports = append(reports, run.Report)
+	}
+
+	report := tester.GroupBy(tester.ReportKeys(tester.MergeRuns(reports...)), group)
+
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: report})
+}
+
+// trendsHandler returns results of the url in all runs, oldest first
+func (r *Router) trendsHandler(w http.ResponseWriter, req *http.Request) {
+	if r.options.Store == nil {
+		r.json(w, http.StatusNotFound, &response{Message: errRunsHistoryDisabled})
+		return
+	}
+
+	url := req.URL.Query().Get(trendURLParam)
+	if url == "" {
+		r.json(w, http.StatusBadRequest, &response{Message: "url query param is required"})
+		return
+	}
+
+	trend, err := r.options.Store.Trend(url)
+	if err != nil {
+		r.json(w, http.StatusInternalServerError, &response{Message: err.Error()})
+		return
+	}
+
+	r.json(w, http.StatusOK, &response{Message: "successfully", Data: trend})
+}
@@ -1,11 +1,12 @@
 package router
 
 import (
-	"math"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
+	"github.com/tagirmukail/ldtester/internal/selfmon"
 	"github.com/tagirmukail/ldtester/internal/tester"
 
 	jsoniter "github.com/json-iterator/go"
@@ -17,9 +18,12 @@ const (
 )
 
 type response struct {
-	Message        string               `json:"message,omitempty"`
-	LoadTestConfig tester.Configuration `json:"load_test_config"`
-	Data           interface{}          `json:"data,omitempty"`
+	Message        string                          `json:"message,omitempty"`
+	LoadTestConfig *tester.Configuration           `json:"load_test_config,omitempty"`
+	Data           interface{}                     `json:"data,omitempty"`
+	Generator      *selfmon.Report                 `json:"generator,omitempty"`
+	Mix            map[string]tester.MixItem       `json:"mix,omitempty"`
+	Verdicts       map[string]tester.BudgetVerdict `json:"verdicts,omitempty"`
 }
 
 func (r *Router) json(w http.ResponseWriter, status int, data interface{}) {
@@ -32,7 +36,7 @@ func (r *Router) json(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-func (r *Router) testerConfFromReq(c tester.Configuration, req *http.Request) tester.Configuration {
+func (r *Router) testerConfFromReq(c tester.Configuration, req *http.Request) (tester.Configuration, error) {
 	maxIdleConn, _ := r.testerConfSetParamInt(maxIdleConnPerHostHeader, maxIdleConnPerHostParam, req)
 	if maxIdleConn > 0 {
 		c.MaxIdleConnPerHost = maxIdleConn
@@ -53,6 +57,221 @@ func (r *Router) testerConfFromReq(c tester.Configuration, req *http.Request) te
 		c.DisableKeepAlive = disableKeepAlive
 	}
 
+	roundInterval, _ := r.testerConfSetParamInt(roundIntervalHeader, roundIntervalParam, req)
+	if roundInterval > 0 {
+		c.RoundInterval = time.Duration(roundInterval) * time.Millisecond
+	}
+
+	warmUp, _ := r.testerConfSetParamInt(warmUpHeader, warmUpParam, req)
+	if warmUp > 0 {
+		c.WarmUp = time.Duration(warmUp) * time.Second
+	}
+
+	warmUpRequests, _ := r.testerConfSetParamInt(warmUpRequestsHeader, warmUpRequestsParam, req)
+	if warmUpRequests > 0 {
+		c.WarmUpRequests = warmUpRequests
+	}
+
+	coolDown, _ := r.testerConfSetParamInt(coolDownHeader, coolDownParam, req)
+	if coolDown > 0 {
+		c.CoolDown = time.Duration(coolDown) * time.Second
+	}
+
+	pacing, _ := r.testerConfSetParamInt(pacingHeader, pacingParam, req)
+	if pacing > 0 {
+		c.Pacing = time.Duration(pacing) * time.Millisecond
+	}
+
+	mix := r.testerConfReqBool(mixHeader, mixParam, req)
+	if mix {
+		c.Mix = true
+	}
+
+	hostBudget := r.testerConfReqBool(hostBudgetHeader, hostBudgetParam, req)
+	if hostBudget {
+		c.HostBudget = true
+	}
+
+	rateLimitMode := r.testerConfReqString(rateLimitModeHeader, rateLimitModeParam, req)
+	if rateLimitMode != "" {
+		c.RateLimit.Mode = rateLimitMode
+	}
+
+	rateLimitRetries, _ := r.testerConfSetParamInt(rateLimitRetriesHeader, rateLimitRetriesParam, req)
+	if rateLimitRetries > 0 {
+		c.RateLimit.MaxRetries = rateLimitRetries
+	}
+
+	err := c.RateLimit.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	tlsVerify := r.testerConfReqBool(tlsVerifyHeader, tlsVerifyParam, req)
+	if tlsVerify {
+		c.TLS.Verify = &tlsVerify
+	}
+
+	tlsMinVersion := r.testerConfReqString(tlsMinVersionHeader, tlsMinVersionParam, req)
+	if tlsMinVersion != "" {
+		c.TLS.MinVersion = tlsMinVersion
+	}
+
+	tlsMaxVersion := r.testerConfReqString(tlsMaxVersionHeader, tlsMaxVersionParam, req)
+	if tlsMaxVersion != "" {
+		c.TLS.MaxVersion = tlsMaxVersion
+	}
+
+	tlsServerName := r.testerConfReqString(tlsServerNameHeader, tlsServerNameParam, req)
+	if tlsServerName != "" {
+		c.TLS.ServerName = tlsServerName
+	}
+
+	resolve := r.testerConfReqStrings(resolveHeader, resolveParam, req)
+	if len(resolve) > 0 {
+		c.Resolver.Resolve = resolve
+	}
+
+	dnsServer := r.testerConfReqString(dnsServerHeader, dnsServerParam, req)
+	if dnsServer != "" {
+		c.Resolver.Server = dnsServer
+	}
+
+	dnsCache := r.testerConfReqBool(dnsCacheHeader, dnsCacheParam, req)
+	if dnsCache {
+		c.Resolver.Cache = true
+	}
+
+	dnsRoundRobin := r.testerConfReqBool(dnsRoundRobinHeader, dnsRoundRobinParam, req)
+	if dnsRoundRobin {
+		c.Resolver.RoundRobin = true
+	}
+
+	dnsPrefer := r.testerConfReqString(dnsPreferHeader, dnsPreferParam, req)
+	if dnsPrefer != "" {
+		c.Resolver.Prefer = dnsPrefer
+	}
+
+	err = c.Resolver.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	proxyURL := r.testerConfReqString(proxyHeader, proxyParam, req)
+	if proxyURL != "" {
+		c.Proxy = proxyconf.Options{URL: proxyURL}
+	}
+
+	proxyFromEnv := r.testerConfReqBool(proxyFromEnvHeader, proxyFromEnvParam, req)
+	if proxyFromEnv {
+		c.Proxy = proxyconf.Options{FromEnvironment: true}
+	}
+
+	err = c.Proxy.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	localAddrs := r.testerConfReqStrings(localAddrHeader, localAddrParam, req)
+	if len(localAddrs) > 0 {
+		c.Dial.LocalAddrs = localAddrs
+	}
+
+	localAddrMode := r.testerConfReqString(localAddrModeHeader, localAddrModeParam, req)
+	if localAddrMode != "" {
+		c.Dial.LocalAddrMode = localAddrMode
+	}
+
+	unixSocket := r.testerConfReqString(unixSocketHeader, unixSocketParam, req)
+	if unixSocket != "" {
+		c.Dial.UnixSocket = unixSocket
+	}
+
+	err = c.Dial.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	useHTTP2 := r.testerConfReqBool(http2Header, http2Param, req)
+	if useHTTP2 {
+		c.UseHTTP2 = true
+	}
+
+	h2c := r.testerConfReqBool(h2cHeader, h2cParam, req)
+	if h2c {
+		c.HTTP2.H2C = true
+	}
+
+	h2MaxInFlight, _ := r.testerConfSetParamInt(h2MaxInFlightHeader, h2MaxInFlightParam, req)
+	if h2MaxInFlight > 0 {
+		c.HTTP2.MaxInFlight = h2MaxInFlight
+	}
+
+	h2Connections, _ := r.testerConfSetParamInt(h2ConnectionsHeader, h2ConnectionsParam, req)
+	if h2Connections > 0 {
+		c.HTTP2.Connections = h2Connections
+	}
+
+	err = c.HTTP2.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	cookies := r.testerConfReqBool(cookiesHeader, cookiesParam, req)
+	if cookies {
+		c.Cookies.Enabled = true
+	}
+
+	cookiesShared := r.testerConfReqBool(cookiesSharedHeader, cookiesSharedParam, req)
+	if cookiesShared {
+		c.Cookies.Shared = true
+	}
+
+	cookiesClear := r.testerConfReqBool(cookiesClearHeader, cookiesClearParam, req)
+	if cookiesClear {
+		c.Cookies.ClearPerIteration = true
+	}
+
+	err = c.Cookies.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	redirects := r.testerConfReqString(redirectsHeader, redirectsParam, req)
+	if redirects != "" {
+		c.Redirects.Policy = redirects
+	}
+
+	redirectMaxHops, _ := r.testerConfSetParamInt(redirectMaxHopsHeader, redirectMaxHopsParam, req)
+	if redirectMaxHops > 0 {
+		c.Redirects.MaxHops = redirectMaxHops
+	}
+
+	err = c.Redirects.Validate()
+	if err != nil {
+		return c, err
+	}
+
+	responseBody := r.testerConfReqString(responseBodyHeader, responseBodyParam, req)
+	if responseBody != "" {
+		c.ResponseBody = responseBody
+	}
+
+	err = tester.ValidateResponseBody(c.ResponseBody)
+	if err != nil {
+		return c, err
+	}
+
+	thinkTimeSpec := r.testerConfReqString(thinkTimeHeader, thinkTimeParam, req)
+	if thinkTimeSpec != "" {
+		thinkTime, err := tester.ParseThinkTime(thinkTimeSpec)
+		if err != nil {
+			return c, err
+		}
+
+		c.ThinkTime = thinkTime
+	}
+
 	reqMethod := r.testerConfReqString(reqMethodHeader, reqMethodParam, req)
 	if reqMethod != "" {
 		c.Method = reqMethod
@@ -68,7 +287,12 @@ func (r *Router) testerConfFromReq(c tester.Configuration, req *http.Request) te
 		c.UserAgent = userAgent
 	}
 
-	return c
+	return c, nil
+}
+
+// groupBy returns the grouping of the report from the request
+func (r *Router) groupBy(req *http.Request) (tester.Group, error) {
+	return tester.ParseGroup(r.testerConfReqString(groupByHeader, groupByParam, req))
 }
 
 func (r *Router) testerConfSetParamInt(header, queryParam string, req *http.Request) (int, error) {
@@ -123,45 +347,12 @@ func (r *Router) testerConfReqString(header, queryParam string, req *http.Reques
 	return val
 }
 
-// reportMergeByHost merges all reports items by hosts
-func reportMergeByHost(report map[tester.Key]tester.Item) map[string]tester.Item {
-	var result = make(map[string]tester.Item)
-
-	hostURLsCounters := make(map[string]int)
-
-	for key, item := range report {
-		resultItemByHost := result[key.Host]
-
-		if item.MaxReqTime > resultItemByHost.MaxReqTime {
-			resultItemByHost.MaxReqTime = item.MaxReqTime
-		}
-
-		resultItemByHost.SlowReqCount += item.SlowReqCount
-		resultItemByHost.ErrRequestCount += item.ErrRequestCount
-		resultItemByHost.TotalReqCount += item.TotalReqCount
-
-		count := hostURLsCounters[key.Host]
-		resultItemByHost.RecommendReqCount = calcAVG(
-			count,
-			float64(resultItemByHost.RecommendReqCount),
-			float64(item.RecommendReqCount),
-		)
-		hostURLsCounters[key.Host] = count + 1
-
-		result[key.Host] = resultItemByHost
+// testerConfReqStrings returns all values of the header or all values of the query param
+func (r *Router) testerConfReqStrings(header, queryParam string, req *http.Request) []string {
+	values := req.Header.Values(header)
+	if len(values) > 0 {
+		return values
 	}
 
-	return result
-}
-
-func calcAVG(currentCount int, currentVal, val float64) int {
-	if currentCount < 1 {
-		return int(math.Round(val))
-	}
-
-	currentSum := float64(currentCount) * currentVal
-
-	result := (currentSum + val) / float64(currentCount+1)
-
-	return int(math.Round(result))
+	return req.URL.Query()[queryParam]
 }
@@ -0,0 +1,57 @@
+//go:build !windows
+// +build !windows
+
+package selfmon
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// cpuTime returns user and system cpu time of the process
+func cpuTime() (time.Duration, bool) {
+	usage := &syscall.Rusage{}
+
+	err := syscall.Getrusage(syscall.RUSAGE_SELF, usage)
+	if err != nil {
+		return 0, false
+	}
+
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
+
+// openFDsCount returns the number of open file descriptors of the process
+func openFDsCount() (int, bool) {
+	dir := "/dev/fd"
+	if runtime.GOOS == "linux" {
+		dir = "/proc/self/fd"
+	}
+
+	f, err := os.Open(dir)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return 0, false
+	}
+
+	// the descriptor of the opened directory
+	return len(names) - 1, true
+}
+
+// fdLimit returns the soft limit of open file descriptors
+func fdLimit() (uint64, bool) {
+	limit := &syscall.Rlimit{}
+
+	err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, limit)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint64(limit.Cur), true
+}
@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package selfmon
+
+import (
+	"time"
+)
+
+// cpuTime is not supported on windows
+func cpuTime() (time.Duration, bool) {
+	return 0, false
+}
+
+// openFDsCount is not supported on windows
+func openFDsCount() (int, bool) {
+	return 0, false
+}
+
+// fdLimit is not supported on windows
+func fdLimit() (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,287 @@
+package selfmon
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+const (
+	defaultInterval = time.Second
+
+	// CPU is saturated if its usage is over the threshold in this number of samples in a row
+	saturatedSamples = 3
+
+	schedProbeInterval = 10 * time.Millisecond
+)
+
+// Options represents thresholds of the load generator saturation
+type Options struct {
+	Interval          time.Duration
+	CPUThreshold      float64 // percent of all cpus
+	FDThreshold       float64 // percent of the open files limit
+	SchedLagThreshold time.Duration
+	Abort             bool // stop the load test when the generator is saturated
+}
+
+func FromConfig(cfg config.SelfMonitor) Options {
+	return Options{
+		Interval:          defaultInterval,
+		CPUThreshold:      cfg.CPUThreshold,
+		FDThreshold:       cfg.FDThreshold,
+		SchedLagThreshold: time.Duration(cfg.SchedLagThreshold) * time.Millisecond,
+		Abort:             cfg.Abort,
+	}
+}
+
+// Report represents resources usage of the load generator during the load test
+type Report struct {
+	Samples       int      `json:"samples"`
+	CPUUsageAvg   float64  `json:"cpu_usage_avg"` // percent of all cpus
+	CPUUsageMax   float64  `json:"cpu_usage_max"`
+	GoroutinesMax int      `json:"goroutines_max"`
+	GCCount       uint32   `json:"gc_count"`
+	GCPauseTotal  float64  `json:"gc_pause_total"` // sec
+	GCPauseMax    float64  `json:"gc_pause_max"`   // sec
+	OpenFDsMax    int      `json:"open_fds_max"`
+	FDLimit       uint64   `json:"fd_limit"`
+	SchedLagMax   float64  `json:"sched_lag_max"` // sec
+	Saturated     bool     `json:"saturated"`     // the generator was the bottleneck, results are not reliable
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+// Monitor samples resources usage of the process
+type Monitor struct {
+	mx sync.Mutex
+
+	opts Options
+	log  logrus.FieldLogger
+
+	report   Report
+	cpuTotal float64
+	cpuOver  int
+	warned   map[string]bool
+
+	lastWall  time.Time
+	lastCPU   time.Duration
+	lastNumGC uint32
+	schedLag  time.Duration // max lag of the current sample
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func New(opts Options, log logrus.FieldLogger) *Monitor {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+
+	return &Monitor{
+		opts:   opts,
+		log:    log,
+		warned: make(map[string]bool),
+	}
+}
+
+// Start starts sampling until ctx is done or Stop is called, abort is called when the generator
+// is saturated and the abort is enabled
+func (m *Monitor) Start(ctx context.Context, abort func()) {
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+
+	m.lastWall = time.Now()
+	m.lastCPU, _ = cpuTime()
+
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+	m.lastNumGC = ms.NumGC
+
+	go m.probeSched(ctx)
+
+	go func() {
+		defer close(m.done)
+
+		ticker := time.NewTicker(m.opts.Interval)
+		defer ticker.Stop()
+
+		var aborted bool
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if m.sample() && m.opts.Abort && abort != nil && !aborted {
+					m.log.Error("load test is aborted because the load generator is saturated")
+					abort()
+
+					aborted = true
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops sampling and returns the report
+func (m *Monitor) Stop() *Report {
+	if m.cancel != nil {
+		m.cancel()
+		<-m.done
+	}
+
+	return m.Report()
+}
+
+// Report returns the current report
+func (m *Monitor) Report() *Report {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	report := m.report
+	report.Warnings = append([]string(nil), m.report.Warnings...)
+
+	if report.Samples > 0 {
+		report.CPUUsageAvg = m.cpuTotal / float64(report.Samples)
+	}
+
+	return &report
+}
+
+// probeSched measures how late the goroutine wakes up after sleeping
+func (m *Monitor) probeSched(ctx context.Context) {
+	for {
+		start := time.Now()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(schedProbeInterval):
+		}
+
+		lag := time.Since(start) - schedProbeInterval
+
+		m.mx.Lock()
+		if lag > m.schedLag {
+			m.schedLag = lag
+		}
+		m.mx.Unlock()
+	}
+}
+
+// sample samples resources usage, returns true if the generator is saturated
+func (m *Monitor) sample() bool {
+	now := time.Now()
+	cpu, cpuOk := cpuTime()
+
+	ms := &runtime.MemStats{}
+	runtime.ReadMemStats(ms)
+
+	openFDs, fdsOk := openFDsCount()
+	limit, limitOk := fdLimit()
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	m.report.Samples++
+
+	var saturated bool
+
+	if cpuOk {
+		usage := float64(cpu-m.lastCPU) / float64(now.Sub(m.lastWall)) / float64(runtime.NumCPU()) * 100
+
+		m.cpuTotal += usage
+		if usage > m.report.CPUUsageMax {
+			m.report.CPUUsageMax = usage
+		}
+
+		m.cpuOver++
+		if usage < m.opts.CPUThreshold {
+			m.cpuOver = 0
+		}
+
+		if m.opts.CPUThreshold > 0 && m.cpuOver >= saturatedSamples {
+			saturated = true
+			m.warn("cpu", fmt.Sprintf("cpu usage %.1f%% is over %g%% for %d samples in a row",
+				usage, m.opts.CPUThreshold, m.cpuOver))
+		}
+	}
+
+	m.lastWall, m.lastCPU = now, cpu
+
+	if goroutines := runtime.NumGoroutine(); goroutines > m.report.GoroutinesMax {
+		m.report.GoroutinesMax = goroutines
+	}
+
+	m.sampleGC(ms)
+
+	if fdsOk && openFDs > m.report.OpenFDsMax {
+		m.report.OpenFDsMax = openFDs
+	}
+
+	if limitOk {
+		m.report.FDLimit = limit
+	}
+
+	if fdsOk && limitOk && m.opts.FDThreshold > 0 &&
+		float64(openFDs) >= float64(limit)*m.opts.FDThreshold/100 {
+		saturated = true
+		m.warn("fds", fmt.Sprintf("open files %d are over %g%% of the limit %d",
+			openFDs, m.opts.FDThreshold, limit))
+	}
+
+	if m.schedLag.Seconds() > m.report.SchedLagMax {
+		m.report.SchedLagMax = m.schedLag.Seconds()
+	}
+
+	if m.opts.SchedLagThreshold > 0 && m.schedLag >= m.opts.SchedLagThreshold {
+		saturated = true
+		m.warn("sched", fmt.Sprintf("scheduling lag %s is over %s", m.schedLag, m.opts.SchedLagThreshold))
+	}
+
+	m.schedLag = 0
+
+	if saturated {
+		m.report.Saturated = true
+	}
+
+	return saturated
+}
+
+// sampleGC adds pauses of garbage collections since the last sample, m.mx must be locked
+func (m *Monitor) sampleGC(ms *runtime.MemStats) {
+	count := ms.NumGC - m.lastNumGC
+	if count > uint32(len(ms.PauseNs)) {
+		count = uint32(len(ms.PauseNs))
+	}
+
+	for n := ms.NumGC - count + 1; n <= ms.NumGC; n++ {
+		pause := time.Duration(ms.PauseNs[(n+uint32(len(ms.PauseNs))-1)%uint32(len(ms.PauseNs))])
+
+		m.report.GCPauseTotal += pause.Seconds()
+		if pause.Seconds() > m.report.GCPauseMax {
+			m.report.GCPauseMax = pause.Seconds()
+		}
+	}
+
+	m.report.GCCount += ms.NumGC - m.lastNumGC
+	m.lastNumGC = ms.NumGC
+}
+
+// warn logs the warning once for every kind and adds it to the report, m.mx must be locked
+func (m *Monitor) warn(kind, warning string) {
+	if m.warned[kind] {
+		return
+	}
+
+	m.warned[kind] = true
+	m.report.Warnings = append(m.report.Warnings, warning)
+
+	m.log.WithField("reason", warning).
+		Warn("LOAD GENERATOR IS SATURATED, it is the bottleneck instead of the target and results are not reliable")
+}
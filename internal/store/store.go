@@ -0,0 +1,194 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/tagirmukail/ldtester/internal/selfmon"
+	"github.com/tagirmukail/ldtester/internal/tester"
+	"github.com/tagirmukail/ldtester/internal/url_item"
+)
+
+const (
+	openTimeout = time.Second
+
+	defaultListLimit = 50
+)
+
+var runsBucket = []byte("runs")
+
+var ErrNotFound = errors.New("run not found")
+
+// Run represents one recorded load test run
+type Run struct {
+	ID         uint64                          `json:"id"`
+	Label      string                          `json:"label,omitempty"`
+	StartedAt  time.Time                       `json:"started_at"`
+	FinishedAt time.Time                       `json:"finished_at"`
+	Config     tester.Configuration            `json:"load_test_config"`
+	Items      []url_item.Item                 `json:"items"`
+	Report     map[string]tester.Item          `json:"report"`
+	Generator  *selfmon.Report                 `json:"generator,omitempty"` // resources usage of the load generator
+	Verdicts   map[string]tester.BudgetVerdict `json:"verdicts,omitempty"`
+}
+
+// RunSummary represents short information about the run for the runs list
+type RunSummary struct {
+	ID         uint64    `json:"id"`
+	Label      string    `json:"label,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	URLsCount  int       `json:"urls_count"`
+}
+
+// TrendPoint represents the result of one url in one run
+type TrendPoint struct {
+	RunID     uint64    `json:"run_id"`
+	Label     string    `json:"label,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	tester.Item
+}
+
+// Store represents persistent history of the load test runs in the embedded file database
+type Store struct {
+	db *bolt.DB
+}
+
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(runsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save saves the run and sets its id, secrets of its configuration and urls are not saved
+func (s *Store) Save(run *Run) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(runsBucket)
+
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		run.ID = id
+
+		saved := *run
+		saved.Config = run.Config.Redacted()
+		saved.Items = url_item.Redacted(run.Items)
+
+		data, err := jsoniter.Marshal(&saved)
+		if err != nil {
+			return err
+		}
+
+		return b.Put(idToKey(id), data)
+	})
+}
+
+func (s *Store) Get(id uint64) (Run, error) {
+	var run Run
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(runsBucket).Get(idToKey(id))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		return jsoniter.Unmarshal(data, &run)
+	})
+
+	return run, err
+}
+
+// List returns the latest runs, newest first
+func (s *Store) List(limit int) ([]RunSummary, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	result := make([]RunSummary, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(runsBucket).Cursor()
+
+		for k, v := c.Last(); k != nil && len(result) < limit; k, v = c.Prev() {
+			var run Run
+
+			err := jsoniter.Unmarshal(v, &run)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, RunSummary{
+				ID:         run.ID,
+				Label:      run.Label,
+				StartedAt:  run.StartedAt,
+				FinishedAt: run.FinishedAt,
+				URLsCount:  len(run.Report),
+			})
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// Trend returns results of the url in all runs, oldest first
+func (s *Store) Trend(url string) ([]TrendPoint, error) {
+	result := make([]TrendPoint, 0)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).ForEach(func(k, v []byte) error {
+			var run Run
+
+			err := jsoniter.Unmarshal(v, &run)
+			if err != nil {
+				return err
+			}
+
+			item, ok := run.Report[url]
+			if !ok {
+				return nil
+			}
+
+			result = append(result, TrendPoint{
+				RunID:     run.ID,
+				Label:     run.Label,
+				StartedAt: run.StartedAt,
+				Item:      item,
+			})
+
+			return nil
+		})
+	})
+
+	return result, err
+}
+
+func idToKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+
+	return key
+}
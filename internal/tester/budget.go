@@ -0,0 +1,135 @@
+package tester
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/tagirmukail/ldtester/internal/url_item"
+)
+
+// BudgetVerdict represents the capacity of urls which share one load profile: urls of the host or urls of the mix
+type BudgetVerdict struct {
+	URLs           int    `json:"urls"`
+	MaxConcurrency int    `json:"max_concurrency"`      // concurrency of the last round of the load profile finished without throttling
+	Saturated      bool   `json:"saturated"`            // the budget is throttled, otherwise the capacity is at least MaxConcurrency
+	FailedURL      string `json:"failed_url,omitempty"` // the url which throttled the budget
+}
+
+// Merge returns the verdict of the budget run by several load generators at the same time,
+// every generator sends its share of the same load profile, so the profile passed until the first generator was throttled
+func (v BudgetVerdict) Merge(o BudgetVerdict) BudgetVerdict {
+	result := BudgetVerdict{
+		URLs:           v.URLs,
+		MaxConcurrency: minInt(v.MaxConcurrency, o.MaxConcurrency),
+		Saturated:      v.Saturated || o.Saturated,
+		FailedURL:      v.FailedURL,
+	}
+
+	if result.FailedURL == "" {
+		result.FailedURL = o.FailedURL
+	}
+
+	return result
+}
+
+// budgets returns urls which share one load profile by the budget name: urls of every host with HostBudget
+// or all urls of the mix
+func (t *Tester) budgets() map[string][]url_item.Item {
+	result := make(map[string][]url_item.Item)
+
+	for _, item := range t.items {
+		budget := groupAllKey
+		if t.conf.HostBudget {
+			budget = item.Host
+		}
+
+		result[budget] = append(result[budget], item)
+	}
+
+	return result
+}
+
+// runBudgets runs one worker for every budget, urls of the host budget share one transport
+func (t *Tester) runBudgets() {
+	wg := sync.WaitGroup{}
+
+	for budget, items := range t.budgets() {
+		budget := budget
+		items := items
+
+		clients, err := t.budgetClients(items)
+		if err != nil {
+			t.log.WithError(err).WithField("budget", budget).Error("setup transport failed")
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			t.runMixWorker(budget, items, clients)
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+}
+
+// budgetClients returns http clients of urls of the budget, urls of the host budget share one transport
+// if they have the same tls options and proxy
+func (t *Tester) budgetClients(items []url_item.Item) ([]*http.Client, error) {
+	clients := make([]*http.Client, len(items))
+
+	// shared is the client of the first url without its own transport
+	var shared *http.Client
+
+	for i, item := range items {
+		if t.conf.HostBudget && shared != nil && !ownTransport(item) {
+			clients[i] = shared
+			continue
+		}
+
+		client, err := t.newClient(item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", item.Url, err)
+		}
+
+		clients[i] = client
+
+		if !ownTransport(item) {
+			shared = client
+		}
+	}
+
+	return clients, nil
+}
+
+// ownTransport returns true if the url overrides the transport of the load test
+func ownTransport(item url_item.Item) bool {
+	return item.TLS != nil || item.Proxy != nil
+}
+
+// setVerdict saves the verdict of the budget
+func (t *Tester) setVerdict(budget string, verdict BudgetVerdict) {
+	t.verdictsMx.Lock()
+	defer t.verdictsMx.Unlock()
+
+	if t.verdicts == nil {
+		t.verdicts = make(map[string]BudgetVerdict)
+	}
+
+	t.verdicts[budget] = verdict
+}
+
+// Verdicts returns capacity verdicts by budget: by host with HostBudget or "all" for the mix,
+// it is empty if urls don't share load profiles
+func (t *Tester) Verdicts() map[string]BudgetVerdict {
+	t.verdictsMx.Lock()
+	defer t.verdictsMx.Unlock()
+
+	result := make(map[string]BudgetVerdict, len(t.verdicts))
+	for budget, verdict := range t.verdicts {
+		result[budget] = verdict
+	}
+
+	return result
+}
@@ -0,0 +1,86 @@
+package tester
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/tagirmukail/ldtester/internal/dialer"
+)
+
+// connTracker counts open connections of hosts
+type connTracker struct {
+	mx    sync.Mutex
+	hosts map[string]*openConns
+}
+
+type openConns struct {
+	open int
+	peak int
+}
+
+// dial returns dial which counts open connections of the host
+func (c *connTracker) dial(host string, dial dialer.DialFunc) dialer.DialFunc {
+	if dial == nil {
+		dial = dialer.New(nil).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		c.add(host, 1)
+
+		return &trackedConn{Conn: conn, release: func() { c.add(host, -1) }}, nil
+	}
+}
+
+func (c *connTracker) add(host string, delta int) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.hosts == nil {
+		c.hosts = make(map[string]*openConns)
+	}
+
+	conns, ok := c.hosts[host]
+	if !ok {
+		conns = &openConns{}
+		c.hosts[host] = conns
+	}
+
+	conns.open += delta
+	if conns.open > conns.peak {
+		conns.peak = conns.open
+	}
+}
+
+// peak returns the peak of open connections of the host
+func (c *connTracker) peak(host string) int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	conns, ok := c.hosts[host]
+	if !ok {
+		return 0
+	}
+
+	return conns.peak
+}
+
+// trackedConn releases its place of open connections on the first close
+type trackedConn struct {
+	net.Conn
+
+	once    sync.Once
+	release func()
+}
+
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+
+	return err
+}
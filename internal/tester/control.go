@@ -0,0 +1,92 @@
+package tester
+
+import (
+	"context"
+	"sync"
+)
+
+// control represents controls of the running load test: pause of all workers and stop of single urls
+type control struct {
+	mx       sync.Mutex
+	paused   bool
+	resumeCh chan struct{}
+	cancels  map[Key]context.CancelFunc
+}
+
+func newControl() *control {
+	return &control{
+		cancels: make(map[Key]context.CancelFunc),
+	}
+}
+
+// Pause pauses all workers before their next round, requests in flight are not interrupted
+func (t *Tester) Pause() {
+	t.control.mx.Lock()
+	defer t.control.mx.Unlock()
+
+	if t.control.paused {
+		return
+	}
+
+	t.control.paused = true
+	t.control.resumeCh = make(chan struct{})
+}
+
+func (t *Tester) Resume() {
+	t.control.mx.Lock()
+	defer t.control.mx.Unlock()
+
+	if !t.control.paused {
+		return
+	}
+
+	t.control.paused = false
+	close(t.control.resumeCh)
+}
+
+func (t *Tester) Paused() bool {
+	t.control.mx.Lock()
+	defer t.control.mx.Unlock()
+
+	return t.control.paused
+}
+
+// StopItem stops the worker of the url, its requests in flight are canceled and not reported
+func (t *Tester) StopItem(key Key) {
+	t.control.mx.Lock()
+	defer t.control.mx.Unlock()
+
+	cancel, ok := t.control.cancels[key]
+	if ok {
+		cancel()
+	}
+}
+
+// itemContext returns the context of the url worker which is canceled by StopItem
+func (t *Tester) itemContext(key Key) context.Context {
+	t.control.mx.Lock()
+	defer t.control.mx.Unlock()
+
+	ctx, cancel := context.WithCancel(t.shutdownCtx)
+	t.control.cancels[key] = cancel
+
+	return ctx
+}
+
+// waitResumed waits while the load test is paused, returns false if the context is done
+func (t *Tester) waitResumed(ctx context.Context) bool {
+	t.control.mx.Lock()
+	paused, resumeCh := t.control.paused, t.control.resumeCh
+	t.control.mx.Unlock()
+
+	if !paused {
+		return true
+	}
+
+	select {
+	case <-resumeCh:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,228 @@
+package tester
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+const httpOnlyPrefix = "#HttpOnly_"
+
+var errInvalidCookies = errors.New("invalid cookies")
+
+// Cookies represents cookie jars of virtual users, requests are stateless if jars are disabled
+type Cookies struct {
+	Enabled           bool   `json:"enabled"`
+	Shared            bool   `json:"shared,omitempty"`              // one jar of all virtual users
+	File              string `json:"file,omitempty"`                // cookies of the netscape format preloaded to every jar
+	ClearPerIteration bool   `json:"clear_per_iteration,omitempty"` // the jar of the virtual user is cleared before its iteration
+}
+
+func CookiesFromConfig(cfg config.Cookies) Cookies {
+	return Cookies{
+		Enabled:           cfg.Enabled,
+		Shared:            cfg.Shared,
+		File:              cfg.File,
+		ClearPerIteration: cfg.ClearPerIteration,
+	}
+}
+
+// Validate checks the file of cookies and options of jars
+func (c Cookies) Validate() error {
+	if c.Shared && c.ClearPerIteration {
+		return fmt.Errorf("%w: the shared jar can't be cleared per iteration", errInvalidCookies)
+	}
+
+	_, err := newJars(c)
+
+	return err
+}
+
+type preloadCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// jarKey is the key of the jar of the virtual user of the worker group: the worker of the url
+// or the budget of the mix worker
+type jarKey struct {
+	group string
+	vu    int
+}
+
+// jars keeps cookie jars of virtual users, virtual users with the same number share the jar across urls
+// of the mix, so the session of the login url is used by other urls of the mix
+type jars struct {
+	opts    Cookies
+	preload []preloadCookie
+
+	mx   sync.Mutex
+	jars map[jarKey]http.CookieJar
+}
+
+func newJars(opts Cookies) (*jars, error) {
+	j := &jars{
+		opts: opts,
+		jars: make(map[jarKey]http.CookieJar),
+	}
+
+	if opts.File == "" {
+		return j, nil
+	}
+
+	preload, err := loadCookies(opts.File)
+	if err != nil {
+		return nil, err
+	}
+
+	j.preload = preload
+
+	return j, nil
+}
+
+// jar returns the jar of the virtual user of the group, the new jar if the virtual user has no jar
+func (j *jars) jar(group string, vu int) http.CookieJar {
+	key := jarKey{group: group, vu: vu}
+	if j.opts.Shared {
+		key = jarKey{}
+	}
+
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	jar, ok := j.jars[key]
+	if ok {
+		return jar
+	}
+
+	// cookiejar.New returns no error without options
+	newJar, _ := cookiejar.New(nil)
+	for _, c := range j.preload {
+		newJar.SetCookies(c.url, []*http.Cookie{c.cookie})
+	}
+
+	j.jars[key] = newJar
+
+	return newJar
+}
+
+// clear removes jars of virtual users of the group, virtual users get new jars with their next requests
+func (j *jars) clear(group string) {
+	j.mx.Lock()
+	defer j.mx.Unlock()
+
+	for key := range j.jars {
+		if key.group == group {
+			delete(j.jars, key)
+		}
+	}
+}
+
+type jarGroupKey struct{}
+
+// withJarGroup returns the context of the worker group which owns jars of its virtual users
+func withJarGroup(ctx context.Context, group string) context.Context {
+	return context.WithValue(ctx, jarGroupKey{}, group)
+}
+
+// clearJars clears jars of virtual users of the group before the round with ClearPerIteration,
+// it is called by the worker of the group while none of its iterations are in flight
+func (t *Tester) clearJars(group string) {
+	if t.jars == nil || !t.conf.Cookies.ClearPerIteration {
+		return
+	}
+
+	t.jars.clear(group)
+}
+
+// withJar returns the copy of the client with the jar of the virtual user of ctx, the client itself if jars are disabled
+func (t *Tester) withJar(ctx context.Context, client *http.Client) *http.Client {
+	if t.jars == nil {
+		return client
+	}
+
+	vu, _ := ctx.Value(vuKey{}).(int)
+	group, _ := ctx.Value(jarGroupKey{}).(string)
+
+	c := *client
+	c.Jar = t.jars.jar(group, vu)
+
+	return &c
+}
+
+// loadCookies loads cookies of the netscape format like cookies of curl -c:
+// domain, include subdomains, path, secure, expires, name, value separated by tabs
+func loadCookies(path string) ([]preloadCookie, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := make([]preloadCookie, 0)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		line = strings.TrimPrefix(line, httpOnlyPrefix)
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("%w in %s: expected 7 fields separated by tabs: %q", errInvalidCookies, path, line)
+		}
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %s: %s", errInvalidCookies, path, err)
+		}
+
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		}
+
+		// cookies of subdomains are domain cookies, others are host-only cookies
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		result = append(result, preloadCookie{
+			url:    &url.URL{Scheme: scheme, Host: host, Path: fields[2]},
+			cookie: cookie,
+		})
+	}
+
+	return result, scanner.Err()
+}
@@ -0,0 +1,47 @@
+package tester
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// TestJarsGroups checks that virtual users of different workers don't share jars
+// and the cleared group doesn't drop sessions of other groups
+func TestJarsGroups(t *testing.T) {
+	j, err := newJars(Cookies{Enabled: true, ClearPerIteration: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &url.URL{Scheme: "http", Host: "test.com", Path: "/"}
+	session := []*http.Cookie{{Name: "session", Value: "1"}}
+
+	j.jar("http://test.com/login", 0).SetCookies(u, session)
+	j.jar("http://test.com/cart", 0).SetCookies(u, session)
+
+	if len(j.jar("http://test.com/login", 1).Cookies(u)) != 0 {
+		t.Fatal("virtual users of the group share the jar")
+	}
+
+	j.clear("http://test.com/login")
+
+	if len(j.jar("http://test.com/login", 0).Cookies(u)) != 0 {
+		t.Fatal("the jar of the cleared group keeps cookies")
+	}
+
+	if len(j.jar("http://test.com/cart", 0).Cookies(u)) != 1 {
+		t.Fatal("the jar of the other group is cleared")
+	}
+
+	shared, err := newJars(Cookies{Enabled: true, Shared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared.jar("http://test.com/login", 0).SetCookies(u, session)
+
+	if len(shared.jar("http://test.com/cart", 1).Cookies(u)) != 1 {
+		t.Fatal("virtual users don't share the shared jar")
+	}
+}
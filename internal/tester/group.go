@@ -0,0 +1,94 @@
+package tester
+
+import (
+	"fmt"
+	nurl "net/url"
+)
+
+// Group represents the grouping of report items
+type Group string
+
+const (
+	GroupByURL  Group = "url"
+	GroupByHost Group = "host"
+	GroupByAll  Group = "all"
+
+	groupAllKey = "all"
+)
+
+// ParseGroup returns the group by its name, empty name is the group by url
+func ParseGroup(name string) (Group, error) {
+	switch g := Group(name); g {
+	case "":
+		return GroupByURL, nil
+	case GroupByURL, GroupByHost, GroupByAll:
+		return g, nil
+	default:
+		return "", fmt.Errorf("unknown group %q, expected one of: url, host, all", name)
+	}
+}
+
+// GroupBy merges report items of urls running at the same time into items by url, host or one item "all"
+func GroupBy(report map[Key]Item, group Group) map[string]Item {
+	result := make(map[string]Item)
+
+	for key, item := range report {
+		var groupKey string
+
+		switch group {
+		case GroupByHost:
+			groupKey = key.Host
+		case GroupByAll:
+			groupKey = groupAllKey
+		default:
+			groupKey = key.URL
+		}
+
+		existItem, ok := result[groupKey]
+		if !ok {
+			result[groupKey] = item.Clone()
+			continue
+		}
+
+		result[groupKey] = existItem.Merge(item)
+	}
+
+	return result
+}
+
+// ReportKeys returns the report by url with keys of urls hosts
+func ReportKeys(report map[string]Item) map[Key]Item {
+	result := make(map[Key]Item, len(report))
+
+	for url, item := range report {
+		key := Key{URL: url}
+
+		parsedURL, err := nurl.Parse(url)
+		if err == nil {
+			key.Host = parsedURL.Hostname()
+		}
+
+		result[key] = item
+	}
+
+	return result
+}
+
+// MergeRuns merges reports by url of repeated runs
+func MergeRuns(reports ...map[string]Item) map[string]Item {
+	result := make(map[string]Item)
+
+	for _, report := range reports {
+		for url, item := range report {
+			existItem, ok := result[url]
+			if !ok {
+				result[url] = item.Clone()
+				continue
+			}
+
+			result[url] = existItem.MergeRun(item)
+		}
+	}
+
+	return result
+}
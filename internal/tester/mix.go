@@ -0,0 +1,283 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+
+	"github.com/tagirmukail/ldtester/internal/url_item"
+)
+
+// MixItem represents the url in the weighted mix
+type MixItem struct {
+	Weight float64 `json:"weight"` // the intended share of requests
+	Share  float64 `json:"share"`  // the share of measured requests
+}
+
+// ValidateWeights checks weights of urls of the mix
+func ValidateWeights(items []url_item.Item) error {
+	for _, item := range items {
+		if item.Weight < 0 {
+			return fmt.Errorf("invalid weight %g of %s, it must not be negative", item.Weight, item.Url)
+		}
+	}
+
+	return nil
+}
+
+// MixReport returns intended and measured shares of urls of the mix by url
+func MixReport(items []url_item.Item, report map[Key]Item) map[string]MixItem {
+	weights := mixWeights(items)
+
+	var total int
+	for _, item := range items {
+		total += report[Key{Host: item.Host, URL: item.Url}].TotalReqCount
+	}
+
+	result := make(map[string]MixItem, len(items))
+
+	for i, item := range items {
+		mixItem := MixItem{Weight: weights[i]}
+
+		if total > 0 {
+			mixItem.Share = float64(report[Key{Host: item.Host, URL: item.Url}].TotalReqCount) / float64(total)
+		}
+
+		result[item.Url] = mixItem
+	}
+
+	return result
+}
+
+// mixWeights returns weights of urls normalized to the sum 1, the url without the weight has the weight 1
+func mixWeights(items []url_item.Item) []float64 {
+	weights := make([]float64, len(items))
+
+	var sum float64
+	for i, item := range items {
+		weights[i] = item.Weight
+		if weights[i] == 0 {
+			weights[i] = 1
+		}
+
+		sum += weights[i]
+	}
+
+	for i := range weights {
+		weights[i] /= sum
+	}
+
+	return weights
+}
+
+// mix distributes requests of rounds across urls by their weights
+type mix struct {
+	weights []float64
+	credits []float64 // not sent parts of requests of previous rounds
+}
+
+func newMix(items []url_item.Item) *mix {
+	return &mix{
+		weights: mixWeights(items),
+		credits: make([]float64, len(items)),
+	}
+}
+
+// next returns numbers of requests of urls of the round with numRequests requests,
+// parts of requests which are not sent are carried over to next rounds, so the mix is kept by small rounds too.
+// Stopped urls don't get requests, their weights are distributed across active urls.
+func (m *mix) next(numRequests int, active []bool) []int {
+	result := make([]int, len(m.weights))
+
+	var activeWeight float64
+	for i, w := range m.weights {
+		if active[i] {
+			activeWeight += w
+		}
+	}
+
+	if activeWeight == 0 {
+		return result
+	}
+
+	order := make([]int, 0, len(m.weights))
+	remaining := numRequests
+
+	for i, w := range m.weights {
+		if !active[i] {
+			m.credits[i] = 0
+			continue
+		}
+
+		m.credits[i] += float64(numRequests) * w / activeWeight
+
+		result[i] = int(m.credits[i])
+		remaining -= result[i]
+
+		order = append(order, i)
+	}
+
+	// the rest of requests are sent to urls with the greatest parts of requests
+	sort.SliceStable(order, func(a, b int) bool {
+		return m.credits[order[a]]-float64(result[order[a]]) > m.credits[order[b]]-float64(result[order[b]])
+	})
+
+	for n := 0; n < remaining; n++ {
+		result[order[n%len(order)]]++
+	}
+
+	for i := range result {
+		if active[i] {
+			m.credits[i] -= float64(result[i])
+		}
+	}
+
+	return result
+}
+
+// runMixWorker runs one worker for urls of the budget, every round is distributed across urls by their weights,
+// the worker is stopped when any url is throttled because the mix is not kept without the url
+func (t *Tester) runMixWorker(budget string, items []url_item.Item, clients []*http.Client) {
+	var (
+		numRequests = 1
+		isHandler   bool
+
+		startedAt = time.Now()
+		sent      int
+
+		nextRound time.Time
+
+		m    = newMix(items)
+		keys = make([]Key, len(items))
+		ctxs = make([]context.Context, len(items))
+
+		verdict = BudgetVerdict{URLs: len(items)}
+	)
+
+	defer func() { t.setVerdict(budget, verdict) }()
+
+	isHandlerVal := t.shutdownCtx.Value(IsHandlerKey)
+	if isHandlerVal != nil {
+		isHandler = isHandlerVal.(bool)
+	}
+
+	log := t.log.WithField("budget", budget)
+
+	for i, item := range items {
+		keys[i] = Key{Host: item.Host, URL: item.Url}
+		ctxs[i] = withJarGroup(t.itemContext(keys[i]), budget)
+
+		defer t.notifyConcurrency(keys[i], 0)
+		defer t.warnLateRounds(keys[i])
+	}
+
+	for {
+		paused := t.Paused()
+		t.waitResumed(t.shutdownCtx)
+
+		select {
+		case <-t.shutdownCtx.Done():
+			log.Info("mix worker canceled")
+
+			return
+		default:
+		}
+
+		active := make([]bool, len(items))
+
+		var activeCount int
+		for i, item := range items {
+			count := t.throttlingChecker.Check(item.Url)
+			if count > 0 {
+				verdict.Saturated = true
+				verdict.FailedURL = item.Url
+
+				log.WithField("url", item.Url).WithField("throttling_requests", count).Info("mix worker stopped")
+
+				return
+			}
+
+			active[i] = ctxs[i].Err() == nil
+			if active[i] {
+				activeCount++
+			}
+		}
+
+		if activeCount == 0 {
+			log.Info("mix worker stopped, all urls are stopped")
+			return
+		}
+
+		// the previous round is finished without throttling
+		if numRequests > 1 {
+			verdict.MaxConcurrency = numRequests
+		}
+
+		numRequests++
+
+		intendedStart, lag := t.roundStart(t.shutdownCtx, &nextRound, paused)
+
+		r := round{
+			numRequests:   numRequests,
+			intendedStart: intendedStart,
+			warmUp:        time.Since(startedAt) < t.conf.WarmUp || sent < t.conf.WarmUpRequests,
+		}
+
+		sent += numRequests
+
+		roundRequests := t.share(numRequests)
+		counts := m.next(roundRequests, active)
+
+		for i, key := range keys {
+			if active[i] {
+				t.trackSchedule(key, lag)
+			}
+
+			t.notifyConcurrency(key, counts[i])
+		}
+
+		var bar *pb.ProgressBar
+		if !isHandler && !t.noProgressBar {
+			log.WithField("req_num", numRequests).WithField("mix", counts).Info("started")
+			bar = pb.StartNew(roundRequests)
+		}
+
+		t.clearJars(budget)
+
+		// virtual users of urls are numbered from 0, so the virtual user with the same number
+		// keeps its local address and cookies across urls
+		wg := sync.WaitGroup{}
+		for i, count := range counts {
+			for n := 0; n < count; n++ {
+				wg.Add(1)
+				go func(i, vu int) {
+					defer func() {
+						wg.Done()
+
+						if bar != nil {
+							bar.Increment()
+						}
+					}()
+
+					if ctxs[i].Err() != nil {
+						return
+					}
+
+					t.iterate(withVU(ctxs[i], vu), clients[i], items[i], r)
+				}(i, n)
+			}
+		}
+
+		wg.Wait()
+
+		if bar != nil {
+			log.WithField("req_num", numRequests).Info("finished")
+			bar.Finish()
+		}
+	}
+}
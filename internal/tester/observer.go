@@ -0,0 +1,54 @@
+package tester
+
+import (
+	"time"
+)
+
+// Observer receives live events of the load test, it must be safe for concurrent use
+type Observer interface {
+	// ConcurrencyChanged is called when the worker starts a round with the new concurrency,
+	// concurrency is 0 when the worker is finished
+	ConcurrencyChanged(key Key, concurrency int)
+	RequestStarted(key Key)
+	RequestFinished(key Key, sample Sample)
+}
+
+// Sample represents the result of one request
+type Sample struct {
+	StatusCode int
+	Duration   time.Duration
+	Err        error // context.Canceled if the request is canceled by stop
+}
+
+// AddObserver adds the observer of the load test, it must be called before Run
+func (t *Tester) AddObserver(o Observer) {
+	t.observers = append(t.observers, o)
+}
+
+func (t *Tester) notifyConcurrency(key Key, concurrency int) {
+	for _, o := range t.observers {
+		o.ConcurrencyChanged(key, concurrency)
+	}
+}
+
+func (t *Tester) notifyRequestStarted(key Key) {
+	for _, o := range t.observers {
+		o.RequestStarted(key)
+	}
+}
+
+func (t *Tester) notifyRequestFinished(key Key, result *requestResult) {
+	if len(t.observers) == 0 {
+		return
+	}
+
+	sample := Sample{
+		StatusCode: result.statusCode,
+		Duration:   result.finishDuration,
+		Err:        result.err,
+	}
+
+	for _, o := range t.observers {
+		o.RequestFinished(key, sample)
+	}
+}
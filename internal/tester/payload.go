@@ -0,0 +1,128 @@
+package tester
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Response body modes, how bodies of responses are received
+const (
+	ResponseBodyDiscard = "discard" // the body is read to the end and its content is discarded
+	ResponseBodySkip    = "skip"    // the body is closed unread, its size is the Content-Length of the response
+)
+
+const (
+	acceptEncodingHeader  = "Accept-Encoding"
+	contentEncodingHeader = "Content-Encoding"
+	encodingGzip          = "gzip"
+)
+
+// ValidateResponseBody checks the response body mode
+func ValidateResponseBody(mode string) error {
+	switch mode {
+	case "", ResponseBodyDiscard, ResponseBodySkip:
+	default:
+		return fmt.Errorf("unknown response body mode %q, expected one of: discard, skip", mode)
+	}
+
+	return nil
+}
+
+// payload represents sizes of the request and its response
+type payload struct {
+	reqBytes     int64 // request lines and headers of all hops in the http/1.1 form
+	respBytes    int64 // the status line, headers and the transferred body of the response
+	bodyBytes    int64 // transferred bytes of the body, compressed if the body is encoded
+	decodedBytes int64 // bytes of the decompressed body
+	compressed   bool
+}
+
+// countingReader counts bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
+
+// readBody receives the body of the response by the mode and closes it, gzip bodies are decompressed
+// to count their decompressed bytes
+func (p *payload) readBody(resp *http.Response, mode string) error {
+	defer resp.Body.Close()
+
+	p.respBytes += responseHeaderSize(resp)
+
+	if mode == ResponseBodySkip {
+		if resp.ContentLength > 0 {
+			p.bodyBytes = resp.ContentLength
+			p.respBytes += resp.ContentLength
+		}
+
+		// the size of the decompressed body is unknown until it is read
+		if resp.Header.Get(contentEncodingHeader) == "" {
+			p.decodedBytes = p.bodyBytes
+		}
+
+		return nil
+	}
+
+	wire := &countingReader{r: resp.Body}
+	defer func() { p.respBytes += wire.n }()
+
+	var body io.Reader = wire
+
+	p.compressed = strings.EqualFold(resp.Header.Get(contentEncodingHeader), encodingGzip) && !resp.Uncompressed
+	if p.compressed {
+		gz, err := gzip.NewReader(wire)
+		if err == io.EOF {
+			p.compressed = false
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+
+		body = gz
+	}
+
+	decoded, err := io.Copy(io.Discard, body)
+
+	p.bodyBytes, p.decodedBytes = wire.n, decoded
+
+	return err
+}
+
+// requestLineSize returns the size of the request line of the request in the http/1.1 form
+func requestLineSize(req *http.Request) int64 {
+	return int64(len(req.Method) + len(req.URL.RequestURI()) + len("HTTP/1.1") + 4)
+}
+
+// headerFieldSize returns the size of the header field in the http/1.1 form
+func headerFieldSize(key string, values []string) int64 {
+	var size int64
+	for _, value := range values {
+		size += int64(len(key) + len(value) + 4)
+	}
+
+	return size
+}
+
+// responseHeaderSize returns the size of the status line and headers of the response in the http/1.1 form
+func responseHeaderSize(resp *http.Response) int64 {
+	size := int64(len(resp.Proto)+len(resp.Status)+3) + 2
+	for key, values := range resp.Header {
+		size += headerFieldSize(key, values)
+	}
+
+	return size
+}
@@ -0,0 +1,155 @@
+package tester
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limit modes, how responses 429 and 503 are handled
+const (
+	RateLimitCount   = "count"   // responses are counted only
+	RateLimitCeiling = "ceiling" // the response is the capacity ceiling, the worker is stopped
+	RateLimitBackoff = "backoff" // all requests wait for the Retry-After of the response
+	RateLimitRetry   = "retry"   // the request is retried with jittered exponential backoff
+)
+
+const retryAfterHeader = "Retry-After"
+
+// RateLimit represents handling of rate limited responses
+type RateLimit struct {
+	Mode       string        `json:"mode,omitempty"`
+	MaxRetries int           `json:"max_retries,omitempty"`
+	Backoff    time.Duration `json:"backoff,omitempty"`     // the backoff if the response has no Retry-After, the base of exponential backoff
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"` // the limit of backoff and Retry-After
+}
+
+// DefaultRateLimit returns the rate limit which counts rate limited responses only
+func DefaultRateLimit() RateLimit {
+	return RateLimit{
+		Mode:       RateLimitCount,
+		MaxRetries: 3,
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+}
+
+// Validate checks the mode of the rate limit
+func (rl RateLimit) Validate() error {
+	switch rl.Mode {
+	case "", RateLimitCount, RateLimitCeiling, RateLimitBackoff, RateLimitRetry:
+	default:
+		return fmt.Errorf("unknown rate limit mode %q, expected one of: count, ceiling, backoff, retry", rl.Mode)
+	}
+
+	if rl.MaxRetries < 0 || rl.Backoff < 0 || rl.MaxBackoff < 0 {
+		return fmt.Errorf("invalid rate limit: negative retries or backoff")
+	}
+
+	return nil
+}
+
+// isRateLimited returns true if the status code means the target limits requests
+func isRateLimited(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter returns the duration of Retry-After in seconds or the http date, 0 if it is not set
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+
+	return time.Until(date)
+}
+
+// limit limits the duration by the max backoff
+func (rl RateLimit) limit(d time.Duration) time.Duration {
+	if rl.MaxBackoff > 0 && d > rl.MaxBackoff {
+		return rl.MaxBackoff
+	}
+
+	return d
+}
+
+// retryDelay returns the jittered exponential backoff of the attempt, Retry-After is used if it is longer
+func (rl RateLimit) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	d := rl.limit(rl.exponential(attempt))
+	if d > 0 {
+		d = d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+
+	if retryAfter > d {
+		d = retryAfter
+	}
+
+	return rl.limit(d)
+}
+
+// exponential returns the backoff doubled for every attempt, the doubling stops at MaxBackoff
+// or before the overflow of the duration
+func (rl RateLimit) exponential(attempt int) time.Duration {
+	d := rl.Backoff
+
+	for i := 0; i < attempt && d > 0 && d <= math.MaxInt64/2; i++ {
+		if rl.MaxBackoff > 0 && d >= rl.MaxBackoff {
+			break
+		}
+
+		d *= 2
+	}
+
+	return d
+}
+
+// backoff represents the global backoff of all requests of the load test
+type backoff struct {
+	mx    sync.Mutex
+	until time.Time
+}
+
+// extend extends the backoff until now + d, returns true if the backoff is extended
+func (b *backoff) extend(d time.Duration) bool {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	until := time.Now().Add(d)
+	if !until.After(b.until) {
+		return false
+	}
+
+	b.until = until
+
+	return true
+}
+
+// wait waits until the backoff is finished or ctx is done, returns true if the backoff is active
+func (b *backoff) wait(ctx context.Context) bool {
+	b.mx.Lock()
+	until := b.until
+	b.mx.Unlock()
+
+	d := time.Until(until)
+	if d <= 0 {
+		return false
+	}
+
+	wait(ctx, d)
+
+	return true
+}
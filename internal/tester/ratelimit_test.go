@@ -0,0 +1,32 @@
+package tester
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestRetryDelay checks that delays of many attempts stop growing at MaxBackoff and never overflow
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		name     string
+		rl       RateLimit
+		attempt  int
+		min, max time.Duration
+	}{
+		{"first attempt", RateLimit{Backoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second}, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"third attempt", RateLimit{Backoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second}, 3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{"capped attempt", RateLimit{Backoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second}, 40, 5 * time.Second, 10 * time.Second},
+		{"attempt over the shift", RateLimit{Backoff: 100 * time.Millisecond, MaxBackoff: 10 * time.Second}, 100, 5 * time.Second, 10 * time.Second},
+		{"unlimited backoff", RateLimit{Backoff: 100 * time.Millisecond}, 100, math.MaxInt64 / 4, math.MaxInt64},
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 100; i++ {
+			d := c.rl.retryDelay(c.attempt, 0)
+			if d < c.min || d > c.max {
+				t.Fatalf("%s: delay %v, expected %v..%v", c.name, d, c.min, c.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+// Redirect policies, how redirect responses are followed
+const (
+	RedirectFollow   = "follow"    // redirects are followed up to max hops
+	RedirectNone     = "none"      // the redirect response is the response of the request
+	RedirectSameHost = "same_host" // redirects to the host of the url are followed, others are the response of the request
+)
+
+const defaultRedirectMaxHops = 10
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+// Redirects represents the redirect policy of requests
+type Redirects struct {
+	Policy  string `json:"policy,omitempty"`
+	MaxHops int    `json:"max_hops,omitempty"` // followed redirects of the request, the next redirect fails the request
+}
+
+// DefaultRedirects returns the policy of the http client: redirects are followed up to 10 hops
+func DefaultRedirects() Redirects {
+	return Redirects{
+		Policy:  RedirectFollow,
+		MaxHops: defaultRedirectMaxHops,
+	}
+}
+
+func RedirectsFromConfig(cfg config.Redirects) Redirects {
+	return Redirects{
+		Policy:  cfg.Policy,
+		MaxHops: cfg.MaxHops,
+	}
+}
+
+// Validate checks the policy and max hops of redirects
+func (r Redirects) Validate() error {
+	switch r.Policy {
+	case "", RedirectFollow, RedirectNone, RedirectSameHost:
+	default:
+		return fmt.Errorf("unknown redirect policy %q, expected one of: follow, none, same_host", r.Policy)
+	}
+
+	if r.MaxHops < 0 {
+		return fmt.Errorf("invalid redirects: negative max hops")
+	}
+
+	return nil
+}
+
+func (r Redirects) maxHops() int {
+	if r.MaxHops == 0 {
+		return defaultRedirectMaxHops
+	}
+
+	return r.MaxHops
+}
+
+type redirectsKey struct{}
+
+// redirectChain records redirect responses of the request and durations of its hops
+type redirectChain struct {
+	hopStart time.Time
+	codes    []int           // status codes of followed redirect responses
+	hops     []time.Duration // durations of hops, the first hop is the request of the url
+	blocked  bool            // the redirect isn't followed by the policy
+}
+
+// withRedirectChain returns the context of the request which records its redirects
+func withRedirectChain(ctx context.Context, chain *redirectChain) context.Context {
+	return context.WithValue(ctx, redirectsKey{}, chain)
+}
+
+// finish records the duration of the last hop if the request is redirected
+func (c *redirectChain) finish() {
+	if len(c.hops) > 0 {
+		c.hops = append(c.hops, time.Since(c.hopStart))
+	}
+}
+
+// checkRedirect is CheckRedirect of clients, via is requests of the url and its followed redirects
+func (t *Tester) checkRedirect(req *http.Request, via []*http.Request) error {
+	chain, _ := req.Context().Value(redirectsKey{}).(*redirectChain)
+
+	policy := t.conf.Redirects.Policy
+
+	if policy == RedirectNone || policy == RedirectSameHost && !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		if chain != nil {
+			chain.blocked = true
+		}
+
+		return http.ErrUseLastResponse
+	}
+
+	if len(via) > t.conf.Redirects.maxHops() {
+		return fmt.Errorf("%w: stopped after %d redirects", errTooManyRedirects, len(via)-1)
+	}
+
+	if chain == nil {
+		return nil
+	}
+
+	now := time.Now()
+
+	chain.hops = append(chain.hops, now.Sub(chain.hopStart))
+	chain.codes = append(chain.codes, req.Response.StatusCode)
+	chain.hopStart = now
+
+	return nil
+}
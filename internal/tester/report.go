@@ -2,9 +2,14 @@ package tester
 
 import (
 	"context"
+	"strconv"
 	"time"
+
+	"github.com/tagirmukail/ldtester/internal/histogram"
 )
 
+const statusError = "error"
+
 type report struct {
 	shutdownCtx context.Context
 
@@ -15,63 +20,366 @@ type report struct {
 	globResult *GlobResult
 
 	maxReqDuration time.Duration
+	coolDown       time.Duration
+	corrected      bool // rounds have the schedule, so the corrected latency is recorded
+
+	pending map[Key][]*requestResult // results of the last cool-down duration of urls
 }
 
-func newReport(shutdownCtx context.Context, resultsCh chan *requestResult, maxReqDuration time.Duration) *report {
+func newReport(shutdownCtx context.Context, resultsCh chan *requestResult, maxReqDuration,
+	coolDown time.Duration, corrected bool) *report {
 	return &report{
 		shutdownCtx: shutdownCtx,
 		results:     resultsCh,
 		globResult:  NewResult(),
 		done:        make(chan struct{}),
+		pending:     make(map[Key][]*requestResult),
 
 		maxReqDuration: maxReqDuration,
+		coolDown:       coolDown,
+		corrected:      corrected,
 	}
 }
 
 func (r *report) runReport() {
 	for reqResult := range r.results {
-		key := Key{
-			Host: reqResult.host,
-			URL:  reqResult.urlKey,
+		if reqResult.warmUp {
+			r.discard(reqResult, func(i *Item) { i.WarmUpReqCount++ })
+			continue
+		}
+
+		if r.coolDown <= 0 {
+			r.process(reqResult)
+			continue
 		}
 
-		r.globResult.ProcessItem(key, func(m map[Key]Item, i Item) {
-			defer func() { m[key] = i }()
+		// results are processed when they are older than the cool-down by the last result of the url,
+		// so results of the last cool-down duration before the stop of the url are not processed
+		key := Key{Host: reqResult.host, URL: reqResult.urlKey}
 
-			i.TotalReqCount++
+		pending := append(r.pending[key], reqResult)
+
+		for len(pending) > 0 && reqResult.finishedAt.Sub(pending[0].finishedAt) > r.coolDown {
+			r.process(pending[0])
+			pending = pending[1:]
+		}
+
+		r.pending[key] = pending
+	}
 
-			if reqResult.err != nil {
-				i.ErrRequestCount++
+	for _, pending := range r.pending {
+		for _, reqResult := range pending {
+			r.discard(reqResult, r.coolDownCount(reqResult))
+		}
+	}
 
-				return
-			}
+	r.stop()
+}
 
-			if reqResult.finishDuration.Seconds() > i.MaxReqTime {
-				i.MaxReqTime = reqResult.finishDuration.Seconds()
-			}
+// coolDownCount returns the count of the cool-down result, errors and slow requests of the cool-down are counted
+// separately from statistics, because the load often stops by them
+func (r *report) coolDownCount(reqResult *requestResult) func(i *Item) {
+	return func(i *Item) {
+		i.CoolDownReqCount++
 
-			if reqResult.finishDuration >= r.maxReqDuration {
-				i.SlowReqCount++
+		if reqResult.retried {
+			return
+		}
 
-				return
-			}
+		switch {
+		case reqResult.err != nil:
+			i.CoolDownErrCount++
+		case reqResult.finishDuration >= r.maxReqDuration:
+			i.CoolDownSlowCount++
+		}
+	}
+}
 
-			// increment i.RecommendReqCount only if no err and finish duration less than max request duration
-			//and not exist any error and all request is fast
-			if i.ErrRequestCount == 0 && i.SlowReqCount == 0 {
-				i.RecommendReqCount++
-			}
-		})
+// discard counts the result which is excluded from statistics
+func (r *report) discard(reqResult *requestResult, count func(i *Item)) {
+	key := Key{
+		Host: reqResult.host,
+		URL:  reqResult.urlKey,
 	}
 
-	r.stop()
+	r.globResult.ProcessItem(key, func(m map[Key]Item, i Item) {
+		count(&i)
+		m[key] = i
+	})
 }
 
-func (r *report) stop() {
-	select {
-	case <-r.shutdownCtx.Done():
+// process adds the result to statistics
+func (r *report) process(reqResult *requestResult) {
+	key := Key{
+		Host: reqResult.host,
+		URL:  reqResult.urlKey,
+	}
+
+	r.globResult.ProcessItem(key, func(m map[Key]Item, i Item) {
+		defer func() { m[key] = i }()
+
+		if i.Latency == nil {
+			i.Latency = histogram.New()
+			i.StatusCodes = make(map[string]int)
+		}
+
+		if r.corrected && i.CorrectedLatency == nil {
+			i.CorrectedLatency = histogram.New()
+			i.LatencyCorrected = true
+		}
+
+		if reqResult.rateLimited {
+			i.RateLimitedCount++
+		}
+
+		if reqResult.tlsHandshake != nil {
+			i.addTLSHandshake(reqResult.tlsHandshake)
+		}
+
+		if reqResult.proto != "" {
+			i.addProtocol(reqResult.proto, reqResult.newConn)
+		}
+
+		i.addConn(reqResult)
+
+		if reqResult.authErr {
+			i.AuthErrCount++
+		}
+
+		i.addRedirects(reqResult)
+
+		if reqResult.retried {
+			i.RetryCount++
+
+			return
+		}
+
+		i.TotalReqCount++
+		i.Addrs = addToAddr(i.Addrs, reqResult.remoteAddr, reqResult)
+		i.Sources = addToAddr(i.Sources, reqResult.localAddr, reqResult)
+		i.addPhases(reqResult)
+		i.addToSeries(reqResult.finishedAt.Unix(), reqResult.err != nil)
+
+		if i.MeasuredFrom.IsZero() || reqResult.finishedAt.Before(i.MeasuredFrom) {
+			i.MeasuredFrom = reqResult.finishedAt
+		}
+
+		if reqResult.finishedAt.After(i.MeasuredTo) {
+			i.MeasuredTo = reqResult.finishedAt
+		}
+
+		i.MeasuredDuration = i.MeasuredTo.Sub(i.MeasuredFrom).Seconds()
+
+		if reqResult.err != nil {
+			i.ErrRequestCount++
+			i.StatusCodes[statusError]++
+
+			return
+		}
+
+		i.StatusCodes[strconv.Itoa(reqResult.statusCode)]++
+		i.addPayload(reqResult.payload)
+
+		if reqResult.finishDuration.Seconds() > i.MaxReqTime {
+			i.MaxReqTime = reqResult.finishDuration.Seconds()
+		}
+
+		i.Latency.Record(reqResult.finishDuration)
+		if r.corrected {
+			i.CorrectedLatency.Record(reqResult.correctedDuration)
+		}
+
+		if reqResult.finishDuration >= r.maxReqDuration {
+			i.SlowReqCount++
+
+			return
+		}
+
+		// increment i.RecommendReqCount only if no err and finish duration less than max request duration
+		//and not exist any error and all request is fast
+		if i.ErrRequestCount == 0 && i.SlowReqCount == 0 {
+			i.RecommendReqCount++
+		}
+	})
+}
+
+// addPhases adds latency of phases of the request
+func (i *Item) addPhases(reqResult *requestResult) {
+	add := func(phase string, d time.Duration) {
+		if i.Phases == nil {
+			i.Phases = make(map[string]*histogram.Histogram)
+		}
+
+		if i.Phases[phase] == nil {
+			i.Phases[phase] = histogram.New()
+		}
+
+		i.Phases[phase].Record(d)
+	}
+
+	if reqResult.dnsDuration > 0 {
+		add(PhaseDNS, reqResult.dnsDuration)
+	}
+
+	if reqResult.connectDuration > 0 {
+		add(PhaseConnect, reqResult.connectDuration)
+	}
+
+	if reqResult.proxyConnectDuration > 0 {
+		add(PhaseProxyConnect, reqResult.proxyConnectDuration)
+	}
+
+	if reqResult.err != nil {
+		return
+	}
+
+	add(PhaseSend, reqResult.reqDuration)
+	add(PhaseWait, reqResult.delayDuration)
+	add(PhaseReceive, reqResult.respDuration)
+}
+
+// addRedirects adds redirects of the request and latency of its hops
+func (i *Item) addRedirects(reqResult *requestResult) {
+	if reqResult.redirectBlocked {
+		i.RedirectBlockedCount++
+	}
+
+	if reqResult.redirectErr {
+		i.RedirectErrCount++
+	}
+
+	if len(reqResult.redirectCodes) == 0 {
+		return
+	}
+
+	if i.RedirectHops == nil {
+		i.RedirectHops = make(map[string]*histogram.Histogram)
+		i.RedirectStatusCodes = make(map[string]int)
+	}
+
+	i.RedirectCount += len(reqResult.redirectCodes)
+	i.RedirectedReqCount++
+	i.MaxRedirectHops = maxInt(i.MaxRedirectHops, len(reqResult.redirectCodes))
+
+	for _, code := range reqResult.redirectCodes {
+		i.RedirectStatusCodes[strconv.Itoa(code)]++
+	}
+
+	for n, d := range reqResult.redirectHops {
+		hop := strconv.Itoa(n + 1)
+		if i.RedirectHops[hop] == nil {
+			i.RedirectHops[hop] = histogram.New()
+		}
+
+		i.RedirectHops[hop].Record(d)
+	}
+}
+
+// addPayload adds sizes of the successful request and its response
+func (i *Item) addPayload(p payload) {
+	i.ReqBytes += p.reqBytes
+	i.RespBytes += p.respBytes
+	i.RespBodyBytes += p.bodyBytes
+	i.RespDecodedBytes += p.decodedBytes
+
+	if p.compressed {
+		i.CompressedRespCount++
+	}
+}
+
+// addToAddr adds the result to results of the ip address ip of the connection, returns results of addresses
+func addToAddr(addrs map[string]AddrItem, ip string, reqResult *requestResult) map[string]AddrItem {
+	if ip == "" {
+		return addrs
+	}
+
+	if addrs == nil {
+		addrs = make(map[string]AddrItem)
+	}
+
+	addr := addrs[ip]
+	if addr.Latency == nil {
+		addr.Latency = histogram.New()
+	}
+
+	addr.TotalReqCount++
+
+	if reqResult.err != nil {
+		addr.ErrRequestCount++
+	} else {
+		addr.Latency.Record(reqResult.finishDuration)
+	}
+
+	addrs[ip] = addr
+
+	return addrs
+}
+
+// addConn adds the connection of the request to statistics of connections
+func (i *Item) addConn(reqResult *requestResult) {
+	if reqResult.connOpened {
+		i.ConnOpenedCount++
+	}
+
+	if reqResult.connErr {
+		i.ConnErrCount++
+	}
+
+	i.PeakOpenConns = maxInt(i.PeakOpenConns, reqResult.peakOpenConns)
+
+	if !reqResult.gotConn {
 		return
-	case r.done <- struct{}{}:
+	}
+
+	if reqResult.newConn {
+		i.NewConnCount++
+
 		return
 	}
+
+	i.ReusedConnCount++
+
+	if reqResult.connWasIdle {
+		if i.ConnIdle == nil {
+			i.ConnIdle = histogram.New()
+		}
+
+		i.ConnIdle.Record(reqResult.connIdle)
+	}
+}
+
+// addProtocol adds the response and its new connection to statistics of the protocol
+func (i *Item) addProtocol(proto string, newConn bool) {
+	if i.Protocols == nil {
+		i.Protocols = make(map[string]int)
+		i.ProtocolConns = make(map[string]int)
+	}
+
+	i.Protocols[proto]++
+
+	if newConn {
+		i.ProtocolConns[proto]++
+	}
+}
+
+// addTLSHandshake adds the tls handshake of the new connection
+func (i *Item) addTLSHandshake(h *tlsHandshake) {
+	if i.TLSHandshake == nil {
+		i.TLSHandshake = histogram.New()
+		i.TLSVersions = make(map[string]int)
+		i.TLSCipherSuites = make(map[string]int)
+	}
+
+	i.TLSHandshake.Record(h.duration)
+	i.TLSVersions[h.version]++
+	i.TLSCipherSuites[h.cipherSuite]++
+
+	if h.resumed {
+		i.TLSResumedCount++
+	}
+}
+
+// stop notifies that all results are processed
+func (r *report) stop() {
+	close(r.done)
 }
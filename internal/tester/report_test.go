@@ -0,0 +1,70 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestReportCoolDown checks that the cool-down of the url starts before its last result
+// and errors and slow requests of the cool-down are counted separately from statistics
+func TestReportCoolDown(t *testing.T) {
+	results := make(chan *requestResult)
+
+	r := newReport(context.Background(), results, time.Second, 2*time.Second, false)
+	go r.runReport()
+
+	start := time.Now()
+
+	result := func(url string, offset time.Duration, err error, duration time.Duration) *requestResult {
+		return &requestResult{
+			urlKey:         url,
+			host:           "test.com",
+			statusCode:     200,
+			finishDuration: duration,
+			err:            err,
+			finishedAt:     start.Add(offset),
+		}
+	}
+
+	// the load of url a stops at 10 seconds by the throttling round of errors and slow requests,
+	// url b runs until 20 seconds, so the cool-down of url a isn't hidden by results of url b
+	for s := 0; s <= 20; s++ {
+		results <- result("b", time.Duration(s)*time.Second, nil, time.Millisecond)
+
+		if s <= 8 {
+			results <- result("a", time.Duration(s)*time.Second, nil, time.Millisecond)
+		}
+
+		if s == 10 {
+			results <- result("a", 10*time.Second, errors.New("refused"), time.Millisecond)
+			results <- result("a", 10*time.Second, nil, 2*time.Second)
+		}
+	}
+
+	close(results)
+	<-r.done
+
+	a := r.globResult.GetResult()[Key{Host: "test.com", URL: "a"}]
+
+	if a.TotalReqCount != 8 || a.CoolDownReqCount != 3 {
+		t.Fatalf("url a: processed %d, cool-down %d, expected 8 and 3", a.TotalReqCount, a.CoolDownReqCount)
+	}
+
+	if a.CoolDownErrCount != 1 || a.CoolDownSlowCount != 1 {
+		t.Fatalf("url a: cool-down errors %d, slow %d, expected 1 and 1", a.CoolDownErrCount, a.CoolDownSlowCount)
+	}
+
+	// statistics don't include the cool-down, so errors never exceed processed requests
+	if a.ErrRequestCount != 0 || a.SlowReqCount != 0 || a.StatusCodes[statusError] != 0 {
+		t.Fatalf("url a: errors %d, slow %d, expected no errors and slow requests in statistics",
+			a.ErrRequestCount, a.SlowReqCount)
+	}
+
+	b := r.globResult.GetResult()[Key{Host: "test.com", URL: "b"}]
+
+	if b.TotalReqCount != 18 || b.CoolDownReqCount != 3 {
+		t.Fatalf("url b: processed %d, cool-down %d, expected 18 and 3", b.TotalReqCount, b.CoolDownReqCount)
+	}
+}
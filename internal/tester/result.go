@@ -1,8 +1,12 @@
 package tester
 
 import (
+	"math"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/tagirmukail/ldtester/internal/histogram"
 )
 
 type Key struct {
@@ -22,11 +26,460 @@ type GlobResult struct {
 }
 
 type Item struct {
-	RecommendReqCount int     `json:"recommend_req_count"`
-	TotalReqCount     int     `json:"total_req_count"`
-	ErrRequestCount   int     `json:"err_request_count"`
-	MaxReqTime        float64 `json:"max_req_time"`
-	SlowReqCount      int     `json:"slow_req_count"`
+	RecommendReqCount int                  `json:"recommend_req_count"`
+	TotalReqCount     int                  `json:"total_req_count"`
+	ErrRequestCount   int                  `json:"err_request_count"`
+	MaxReqTime        float64              `json:"max_req_time"`
+	SlowReqCount      int                  `json:"slow_req_count"`
+	Latency           *histogram.Histogram `json:"latency,omitempty"`      // latency of successful requests
+	StatusCodes       map[string]int       `json:"status_codes,omitempty"` // requests by status code, "error" if failed
+	Series            []SeriesBucket       `json:"series,omitempty"`       // requests by unix seconds, oldest first
+
+	// latency of successful requests from their intended send time by the rounds schedule,
+	// it includes the time the requests waited for late rounds (coordinated omission correction),
+	// the correction is inactive without RoundInterval and corrected_latency is omitted
+	CorrectedLatency *histogram.Histogram `json:"corrected_latency,omitempty"`
+	LatencyCorrected bool                 `json:"latency_corrected"`
+	LateRounds       int                  `json:"late_rounds"`      // rounds started later than intended
+	MaxScheduleLag   float64              `json:"max_schedule_lag"` // sec
+
+	// the measured window, requests of warm-up and cool-down are excluded from statistics,
+	// the duration of merged runs is the sum of durations of their windows without gaps between runs,
+	// errors and slow requests of the cool-down are counted separately
+	MeasuredFrom      time.Time `json:"measured_from"`
+	MeasuredTo        time.Time `json:"measured_to"`
+	MeasuredDuration  float64   `json:"measured_duration"` // sec
+	WarmUpReqCount    int       `json:"warm_up_req_count"`
+	CoolDownReqCount  int       `json:"cool_down_req_count"`
+	CoolDownErrCount  int       `json:"cool_down_err_count"`
+	CoolDownSlowCount int       `json:"cool_down_slow_count"`
+
+	// responses 429 and 503 including retried ones, and retries of rate limited requests,
+	// retried responses are not a part of other statistics
+	RateLimitedCount int `json:"rate_limited_count"`
+	RetryCount       int `json:"retry_count"`
+
+	// tls handshakes of new connections, handshakes by negotiated versions and cipher suites
+	TLSHandshake    *histogram.Histogram `json:"tls_handshake,omitempty"`
+	TLSVersions     map[string]int       `json:"tls_versions,omitempty"`
+	TLSCipherSuites map[string]int       `json:"tls_cipher_suites,omitempty"`
+	TLSResumedCount int                  `json:"tls_resumed_count"`
+
+	Addrs   map[string]AddrItem `json:"addrs,omitempty"`   // results by ip addresses of connections
+	Sources map[string]AddrItem `json:"sources,omitempty"` // results by local addresses of connections
+
+	// requests on new and reused connections, idle time of reused idle connections, dialed connections,
+	// requests failed before they got connections, the peak of open connections of the host
+	NewConnCount    int                  `json:"new_conn_count"`
+	ReusedConnCount int                  `json:"reused_conn_count"`
+	ConnIdle        *histogram.Histogram `json:"conn_idle,omitempty"`
+	ConnOpenedCount int                  `json:"conn_opened_count"`
+	ConnErrCount    int                  `json:"conn_err_count"`
+	PeakOpenConns   int                  `json:"peak_open_conns"`
+
+	// failed requests which are not sent because of auth errors: token fetch and signing errors
+	AuthErrCount int `json:"auth_err_count"`
+
+	// responses and new connections by protocols of responses: HTTP/1.1, HTTP/2.0
+	Protocols     map[string]int `json:"protocols,omitempty"`
+	ProtocolConns map[string]int `json:"protocol_conns,omitempty"`
+
+	// followed redirects, requests with at least one followed redirect, the longest redirect chain,
+	// redirects which are not followed by the redirect policy, requests failed by max hops of redirects,
+	// followed redirects by status codes and latency of hops of redirected requests by hop number,
+	// hop 1 is the request of the url
+	RedirectCount        int                             `json:"redirect_count"`
+	RedirectedReqCount   int                             `json:"redirected_req_count"`
+	MaxRedirectHops      int                             `json:"max_redirect_hops"`
+	RedirectBlockedCount int                             `json:"redirect_blocked_count"`
+	RedirectErrCount     int                             `json:"redirect_err_count"`
+	RedirectStatusCodes  map[string]int                  `json:"redirect_status_codes,omitempty"`
+	RedirectHops         map[string]*histogram.Histogram `json:"redirect_hops,omitempty"`
+
+	// bytes of successful requests: request lines and headers in the http/1.1 form, status lines, headers and
+	// transferred bodies of responses, transferred and decompressed bytes of response bodies,
+	// responses with gzip bodies
+	ReqBytes            int64 `json:"req_bytes"`
+	RespBytes           int64 `json:"resp_bytes"`
+	RespBodyBytes       int64 `json:"resp_body_bytes"`
+	RespDecodedBytes    int64 `json:"resp_decoded_bytes"`
+	CompressedRespCount int   `json:"compressed_resp_count"`
+
+	// latency of phases of requests by phase, phases of connections are recorded for new connections only
+	Phases map[string]*histogram.Histogram `json:"phases,omitempty"`
+}
+
+// Phases of requests
+const (
+	PhaseDNS          = "dns"
+	PhaseConnect      = "connect"
+	PhaseProxyConnect = "proxy_connect" // CONNECT of the http proxy or the socks handshake
+	PhaseSend         = "send"
+	PhaseWait         = "wait" // from the sent request to the first response byte
+	PhaseReceive      = "receive"
+)
+
+// AddrItem represents results of requests sent to one ip address of the host
+type AddrItem struct {
+	TotalReqCount   int                  `json:"total_req_count"`
+	ErrRequestCount int                  `json:"err_request_count"`
+	Latency         *histogram.Histogram `json:"latency,omitempty"` // latency of successful requests
+}
+
+// SeriesBucket represents requests finished in one second
+type SeriesBucket struct {
+	Second   int64 `json:"second"`
+	Requests int   `json:"requests"`
+	Errors   int   `json:"errors"`
+}
+
+// Merge returns the item with results of both items which were running at the same time,
+// for example results of the same url from several load generators or results of urls of the same host,
+// the measured duration is the longest one of both items
+func (i Item) Merge(o Item) Item {
+	result := i.merge(o)
+	result.RecommendReqCount = i.RecommendReqCount + o.RecommendReqCount
+	result.MeasuredDuration = math.Max(i.measuredDuration(), o.measuredDuration())
+
+	return result
+}
+
+// MergeRun returns the item with results of both items of repeated runs,
+// the recommended requests count is the count which was reached in every run,
+// the measured duration is the sum of durations of both runs
+func (i Item) MergeRun(o Item) Item {
+	result := i.merge(o)
+	result.MeasuredDuration = i.measuredDuration() + o.measuredDuration()
+
+	switch {
+	case i.TotalReqCount == 0:
+		result.RecommendReqCount = o.RecommendReqCount
+	case o.TotalReqCount == 0:
+		result.RecommendReqCount = i.RecommendReqCount
+	default:
+		result.RecommendReqCount = minInt(i.RecommendReqCount, o.RecommendReqCount)
+	}
+
+	return result
+}
+
+// merge returns the item with summed counters and merged distributions of both items
+func (i Item) merge(o Item) Item {
+	result := Item{
+		TotalReqCount:    i.TotalReqCount + o.TotalReqCount,
+		ErrRequestCount:  i.ErrRequestCount + o.ErrRequestCount,
+		MaxReqTime:       math.Max(i.MaxReqTime, o.MaxReqTime),
+		SlowReqCount:     i.SlowReqCount + o.SlowReqCount,
+		Latency:          histogram.New(),
+		StatusCodes:      make(map[string]int, len(i.StatusCodes)),
+		Series:           mergeSeries(i.Series, o.Series),
+		LatencyCorrected: i.LatencyCorrected && o.LatencyCorrected,
+		LateRounds:       i.LateRounds + o.LateRounds,
+		MaxScheduleLag:   math.Max(i.MaxScheduleLag, o.MaxScheduleLag),
+		MeasuredFrom:     i.MeasuredFrom,
+		MeasuredTo:       i.MeasuredTo,
+		WarmUpReqCount:   i.WarmUpReqCount + o.WarmUpReqCount,
+		CoolDownReqCount: i.CoolDownReqCount + o.CoolDownReqCount,
+
+		CoolDownErrCount:  i.CoolDownErrCount + o.CoolDownErrCount,
+		CoolDownSlowCount: i.CoolDownSlowCount + o.CoolDownSlowCount,
+
+		RateLimitedCount: i.RateLimitedCount + o.RateLimitedCount,
+		RetryCount:       i.RetryCount + o.RetryCount,
+		TLSVersions:      mergeCounts(i.TLSVersions, o.TLSVersions),
+		TLSCipherSuites:  mergeCounts(i.TLSCipherSuites, o.TLSCipherSuites),
+		NewConnCount:     i.NewConnCount + o.NewConnCount,
+		ReusedConnCount:  i.ReusedConnCount + o.ReusedConnCount,
+		ConnOpenedCount:  i.ConnOpenedCount + o.ConnOpenedCount,
+		ConnErrCount:     i.ConnErrCount + o.ConnErrCount,
+		AuthErrCount:     i.AuthErrCount + o.AuthErrCount,
+		PeakOpenConns:    maxInt(i.PeakOpenConns, o.PeakOpenConns),
+		Protocols:        mergeCounts(i.Protocols, o.Protocols),
+		ProtocolConns:    mergeCounts(i.ProtocolConns, o.ProtocolConns),
+		TLSResumedCount:  i.TLSResumedCount + o.TLSResumedCount,
+		Addrs:            mergeAddrs(i.Addrs, o.Addrs),
+		Sources:          mergeAddrs(i.Sources, o.Sources),
+		Phases:           mergePhases(i.Phases, o.Phases),
+
+		RedirectCount:        i.RedirectCount + o.RedirectCount,
+		RedirectedReqCount:   i.RedirectedReqCount + o.RedirectedReqCount,
+		MaxRedirectHops:      maxInt(i.MaxRedirectHops, o.MaxRedirectHops),
+		RedirectBlockedCount: i.RedirectBlockedCount + o.RedirectBlockedCount,
+		RedirectErrCount:     i.RedirectErrCount + o.RedirectErrCount,
+		RedirectStatusCodes:  mergeCounts(i.RedirectStatusCodes, o.RedirectStatusCodes),
+		RedirectHops:         mergePhases(i.RedirectHops, o.RedirectHops),
+
+		ReqBytes:            i.ReqBytes + o.ReqBytes,
+		RespBytes:           i.RespBytes + o.RespBytes,
+		RespBodyBytes:       i.RespBodyBytes + o.RespBodyBytes,
+		RespDecodedBytes:    i.RespDecodedBytes + o.RespDecodedBytes,
+		CompressedRespCount: i.CompressedRespCount + o.CompressedRespCount,
+	}
+
+	if i.TLSHandshake != nil || o.TLSHandshake != nil {
+		result.TLSHandshake = histogram.New()
+		result.TLSHandshake.Merge(i.TLSHandshake)
+		result.TLSHandshake.Merge(o.TLSHandshake)
+	}
+
+	if i.ConnIdle != nil || o.ConnIdle != nil {
+		result.ConnIdle = histogram.New()
+		result.ConnIdle.Merge(i.ConnIdle)
+		result.ConnIdle.Merge(o.ConnIdle)
+	}
+
+	if result.MeasuredFrom.IsZero() || !o.MeasuredFrom.IsZero() && o.MeasuredFrom.Before(result.MeasuredFrom) {
+		result.MeasuredFrom = o.MeasuredFrom
+	}
+
+	if o.MeasuredTo.After(result.MeasuredTo) {
+		result.MeasuredTo = o.MeasuredTo
+	}
+
+	result.Latency.Merge(i.Latency)
+	result.Latency.Merge(o.Latency)
+	if result.LatencyCorrected {
+		result.CorrectedLatency = histogram.New()
+		result.CorrectedLatency.Merge(i.CorrectedLatency)
+		result.CorrectedLatency.Merge(o.CorrectedLatency)
+	}
+
+	for status, count := range i.StatusCodes {
+		result.StatusCodes[status] += count
+	}
+
+	for status, count := range o.StatusCodes {
+		result.StatusCodes[status] += count
+	}
+
+	return result
+}
+
+// Clone returns the item which doesn't share histograms, status codes and series with i
+func (i Item) Clone() Item {
+	if i.Latency != nil {
+		i.Latency = i.Latency.Clone()
+	}
+
+	if i.CorrectedLatency != nil {
+		i.CorrectedLatency = i.CorrectedLatency.Clone()
+	}
+
+	if i.StatusCodes != nil {
+		statusCodes := make(map[string]int, len(i.StatusCodes))
+		for status, count := range i.StatusCodes {
+			statusCodes[status] = count
+		}
+
+		i.StatusCodes = statusCodes
+	}
+
+	if i.TLSHandshake != nil {
+		i.TLSHandshake = i.TLSHandshake.Clone()
+	}
+
+	if i.ConnIdle != nil {
+		i.ConnIdle = i.ConnIdle.Clone()
+	}
+
+	i.Addrs = mergeAddrs(i.Addrs, nil)
+	i.Sources = mergeAddrs(i.Sources, nil)
+	i.Phases = mergePhases(i.Phases, nil)
+	i.RedirectHops = mergePhases(i.RedirectHops, nil)
+	i.RedirectStatusCodes = mergeCounts(i.RedirectStatusCodes, nil)
+	i.TLSVersions = mergeCounts(i.TLSVersions, nil)
+	i.TLSCipherSuites = mergeCounts(i.TLSCipherSuites, nil)
+	i.Protocols = mergeCounts(i.Protocols, nil)
+	i.ProtocolConns = mergeCounts(i.ProtocolConns, nil)
+
+	i.Series = append([]SeriesBucket(nil), i.Series...)
+
+	return i
+}
+
+// mergeAddrs returns the new map with merged results of ip addresses of both maps, nil if both are empty
+func mergeAddrs(a, b map[string]AddrItem) map[string]AddrItem {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	result := make(map[string]AddrItem, len(a))
+
+	for _, m := range []map[string]AddrItem{a, b} {
+		for addr, item := range m {
+			existItem := result[addr]
+
+			latency := histogram.New()
+			latency.Merge(existItem.Latency)
+			latency.Merge(item.Latency)
+
+			result[addr] = AddrItem{
+				TotalReqCount:   existItem.TotalReqCount + item.TotalReqCount,
+				ErrRequestCount: existItem.ErrRequestCount + item.ErrRequestCount,
+				Latency:         latency,
+			}
+		}
+	}
+
+	return result
+}
+
+// mergePhases returns the new map with merged histograms of phases or hops of both maps, nil if both are empty
+func mergePhases(a, b map[string]*histogram.Histogram) map[string]*histogram.Histogram {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	result := make(map[string]*histogram.Histogram, len(a))
+
+	for _, m := range []map[string]*histogram.Histogram{a, b} {
+		for phase, h := range m {
+			if result[phase] == nil {
+				result[phase] = histogram.New()
+			}
+
+			result[phase].Merge(h)
+		}
+	}
+
+	return result
+}
+
+// mergeCounts returns the new map with sums of counts of both maps, nil if both are empty
+func mergeCounts(a, b map[string]int) map[string]int {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	result := make(map[string]int, len(a))
+
+	for k, count := range a {
+		result[k] += count
+	}
+
+	for k, count := range b {
+		result[k] += count
+	}
+
+	return result
+}
+
+// Percentile returns the latency percentile of successful requests in seconds, p is 0..100
+func (i Item) Percentile(p float64) float64 {
+	if i.Latency == nil {
+		return 0
+	}
+
+	return i.Latency.Quantile(p / 100).Seconds()
+}
+
+// CorrectedPercentile returns the corrected latency percentile of successful requests in seconds, p is 0..100
+func (i Item) CorrectedPercentile(p float64) float64 {
+	if i.CorrectedLatency == nil {
+		return 0
+	}
+
+	return i.CorrectedLatency.Quantile(p / 100).Seconds()
+}
+
+// MeanRespSize returns the mean size of responses of successful requests in bytes with headers
+func (i Item) MeanRespSize() float64 {
+	responses := i.TotalReqCount - i.ErrRequestCount
+	if responses <= 0 {
+		return 0
+	}
+
+	return float64(i.RespBytes) / float64(responses)
+}
+
+// ReceiveRate returns bytes of responses per second of the measured window
+func (i Item) ReceiveRate() float64 {
+	return i.rate(i.RespBytes)
+}
+
+// SendRate returns bytes of requests per second of the measured window
+func (i Item) SendRate() float64 {
+	return i.rate(i.ReqBytes)
+}
+
+func (i Item) rate(bytes int64) float64 {
+	window := i.measuredDuration()
+	if window <= 0 {
+		return 0
+	}
+
+	return float64(bytes) / window
+}
+
+// measuredDuration returns the measured duration in seconds, items of the runs history saved without it
+// have the duration of their window
+func (i Item) measuredDuration() float64 {
+	if i.MeasuredDuration > 0 {
+		return i.MeasuredDuration
+	}
+
+	return i.MeasuredTo.Sub(i.MeasuredFrom).Seconds()
+}
+
+// addToSeries counts the request in the bucket of the second
+func (i *Item) addToSeries(second int64, failed bool) {
+	idx := sort.Search(len(i.Series), func(n int) bool { return i.Series[n].Second >= second })
+	if idx == len(i.Series) || i.Series[idx].Second != second {
+		i.Series = append(i.Series, SeriesBucket{})
+		copy(i.Series[idx+1:], i.Series[idx:])
+		i.Series[idx] = SeriesBucket{Second: second}
+	}
+
+	i.Series[idx].Requests++
+
+	if failed {
+		i.Series[idx].Errors++
+	}
+}
+
+// mergeSeries merges sorted series into the new one
+func mergeSeries(a, b []SeriesBucket) []SeriesBucket {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+
+	result := make([]SeriesBucket, 0, len(a)+len(b))
+
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || len(a) > 0 && a[0].Second < b[0].Second:
+			result = append(result, a[0])
+			a = a[1:]
+		case len(a) == 0 || b[0].Second < a[0].Second:
+			result = append(result, b[0])
+			b = b[1:]
+		default:
+			result = append(result, SeriesBucket{
+				Second:   a[0].Second,
+				Requests: a[0].Requests + b[0].Requests,
+				Errors:   a[0].Errors + b[0].Errors,
+			})
+			a, b = a[1:], b[1:]
+		}
+	}
+
+	return result
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
 }
 
 func NewResult() *GlobResult {
@@ -56,6 +509,19 @@ func (r *GlobResult) GetResult() map[Key]Item {
 	return r.m
 }
 
+// Snapshot returns the copy of the current result, it is safe to use while the load test is running
+func (r *GlobResult) Snapshot() map[Key]Item {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	result := make(map[Key]Item, len(r.m))
+	for key, item := range r.m {
+		result[key] = item.Clone()
+	}
+
+	return result
+}
+
 type requestResult struct {
 	urlKey         string
 	host           string
@@ -63,11 +529,44 @@ type requestResult struct {
 	statusCode     int
 	finishDuration time.Duration
 	err            error
-	connDuration   time.Duration
-	dnsDuration    time.Duration
-	reqDuration    time.Duration
-	respDuration   time.Duration
-	delayDuration  time.Duration
+
+	correctedDuration    time.Duration // from the intended send time
+	finishedAt           time.Time
+	warmUp               bool // the request is sent in the warm-up
+	rateLimited          bool // the response is 429 or 503
+	tlsHandshake         *tlsHandshake
+	remoteAddr           string // ip address of the connection
+	localAddr            string // local ip address of the connection, it is set if local addresses are configured
+	proto                string // protocol of the response
+	gotConn              bool
+	newConn              bool
+	connOpened           bool // the connection is dialed by the request
+	connErr              bool
+	authErr              bool            // the request isn't sent because of the authentication error
+	redirectHops         []time.Duration // durations of hops of the redirected request, the first hop is the request of the url
+	redirectCodes        []int           // status codes of followed redirect responses
+	redirectBlocked      bool            // the redirect isn't followed by the redirect policy
+	redirectErr          bool            // the request exceeded max hops of redirects
+	payload              payload         // sizes of the request and its response
+	connWasIdle          bool
+	connIdle             time.Duration // idle time of the reused connection
+	peakOpenConns        int           // the peak of open connections of the host
+	retried              bool          // the rate limited request is retried, the result is not a part of statistics
+	connDuration         time.Duration
+	connectDuration      time.Duration // tcp connection of the new connection
+	proxyConnectDuration time.Duration
+	dnsDuration          time.Duration
+	reqDuration          time.Duration
+	respDuration         time.Duration
+	delayDuration        time.Duration
+}
+
+// tlsHandshake represents the tls handshake of the new connection of the request
+type tlsHandshake struct {
+	duration    time.Duration
+	version     string
+	cipherSuite string
+	resumed     bool
 }
 
 type throttlingChecker struct {
@@ -0,0 +1,199 @@
+package tester
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var mergeStart = time.Unix(1700000000, 0)
+
+// requests returns results of count requests of the url finished every 100ms from start,
+// every 10th request of the odd shift fails and every 25th one is slow
+func requests(url string, start time.Time, count, shift int) []*requestResult {
+	results := make([]*requestResult, 0, count)
+
+	for n := 0; n < count; n++ {
+		result := &requestResult{
+			urlKey:            url,
+			host:              "test.com",
+			statusCode:        200,
+			finishDuration:    time.Duration((n*7+shift*13)%90+1) * time.Millisecond,
+			correctedDuration: time.Duration((n*7+shift*13)%90+5) * time.Millisecond,
+			finishedAt:        start.Add(time.Duration(n) * 100 * time.Millisecond),
+			payload:           payload{reqBytes: 100, respBytes: int64(1000 + shift)},
+		}
+
+		if shift%2 == 1 && n%10 == 0 {
+			result.err = errors.New("refused")
+		}
+
+		if n%25 == 24 {
+			result.finishDuration = 1500 * time.Millisecond
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// reportOf returns the report of results processed as one run
+func reportOf(corrected bool, results ...[]*requestResult) map[Key]Item {
+	ch := make(chan *requestResult)
+
+	r := newReport(context.Background(), ch, time.Second, 0, corrected)
+	go r.runReport()
+
+	for _, part := range results {
+		for _, result := range part {
+			ch <- result
+		}
+	}
+
+	close(ch)
+	<-r.done
+
+	return r.globResult.GetResult()
+}
+
+// itemOf returns the item of the url processed as one run
+func itemOf(url string, results ...[]*requestResult) Item {
+	return reportOf(false, results...)[Key{Host: "test.com", URL: url}]
+}
+
+// TestItemMerge checks that merged items have counters, rates and percentiles of the single run
+// with all their requests
+func TestItemMerge(t *testing.T) {
+	cases := []struct {
+		name     string
+		merged   func() Item
+		combined Item
+	}{
+		{
+			name: "generators of the url",
+			merged: func() Item {
+				return itemOf("a", requests("a", mergeStart, 100, 1)).Merge(itemOf("a", requests("a", mergeStart, 100, 2)))
+			},
+			combined: itemOf("a", requests("a", mergeStart, 100, 1), requests("a", mergeStart, 100, 2)),
+		},
+		{
+			name: "runs of the url",
+			merged: func() Item {
+				first := GroupBy(reportOf(false, requests("a", mergeStart, 100, 1)), GroupByURL)
+				second := GroupBy(reportOf(false, requests("a", mergeStart.Add(time.Hour), 50, 3)), GroupByURL)
+
+				return MergeRuns(first, second)["a"]
+			},
+			// the second run goes right after the first one without the gap
+			combined: itemOf("a", requests("a", mergeStart, 100, 1), requests("a", mergeStart.Add(9900*time.Millisecond), 50, 3)),
+		},
+		{
+			name: "urls of the host",
+			merged: func() Item {
+				return GroupBy(reportOf(false, requests("a", mergeStart, 100, 1), requests("b", mergeStart, 100, 2)), GroupByHost)["test.com"]
+			},
+			combined: itemOf("a", requests("a", mergeStart, 100, 1), requests("a", mergeStart, 100, 2)),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, expected := c.merged(), c.combined
+
+			counters := []struct {
+				name          string
+				got, expected int64
+			}{
+				{"total_req_count", int64(got.TotalReqCount), int64(expected.TotalReqCount)},
+				{"err_request_count", int64(got.ErrRequestCount), int64(expected.ErrRequestCount)},
+				{"slow_req_count", int64(got.SlowReqCount), int64(expected.SlowReqCount)},
+				{"status 200", int64(got.StatusCodes["200"]), int64(expected.StatusCodes["200"])},
+				{"status error", int64(got.StatusCodes[statusError]), int64(expected.StatusCodes[statusError])},
+				{"req_bytes", got.ReqBytes, expected.ReqBytes},
+				{"resp_bytes", got.RespBytes, expected.RespBytes},
+				{"latency count", int64(got.Latency.Count), int64(expected.Latency.Count)},
+			}
+
+			for _, counter := range counters {
+				if counter.got != counter.expected {
+					t.Errorf("%s: %d, expected %d", counter.name, counter.got, counter.expected)
+				}
+			}
+
+			values := []struct {
+				name          string
+				got, expected float64
+			}{
+				{"p50", got.Percentile(50), expected.Percentile(50)},
+				{"p90", got.Percentile(90), expected.Percentile(90)},
+				{"p99", got.Percentile(99), expected.Percentile(99)},
+				{"max_req_time", got.MaxReqTime, expected.MaxReqTime},
+				{"measured_duration", got.MeasuredDuration, expected.MeasuredDuration},
+				{"throughput", got.Throughput(), expected.Throughput()},
+				{"receive rate", got.ReceiveRate(), expected.ReceiveRate()},
+				{"send rate", got.SendRate(), expected.SendRate()},
+				{"mean resp size", got.MeanRespSize(), expected.MeanRespSize()},
+			}
+
+			for _, value := range values {
+				if math.Abs(value.got-value.expected) > 1e-9 {
+					t.Errorf("%s: %v, expected %v", value.name, value.got, value.expected)
+				}
+			}
+		})
+	}
+}
+
+// TestItemMergeRunRecommendCount checks that the recommended count of merged runs is reached in every run
+func TestItemMergeRunRecommendCount(t *testing.T) {
+	first := itemOf("a", requests("a", mergeStart, 100, 2))
+	second := itemOf("a", requests("a", mergeStart, 10, 2))
+
+	if got := first.MergeRun(second).RecommendReqCount; got != second.RecommendReqCount {
+		t.Fatalf("recommend_req_count %d, expected %d", got, second.RecommendReqCount)
+	}
+
+	if got := first.Merge(second).RecommendReqCount; got != first.RecommendReqCount+second.RecommendReqCount {
+		t.Fatalf("recommend_req_count of generators %d, expected %d", got, first.RecommendReqCount+second.RecommendReqCount)
+	}
+}
+
+// TestItemMergeCorrected checks that the merged latency is corrected only if latency of both items is corrected
+func TestItemMergeCorrected(t *testing.T) {
+	key := Key{Host: "test.com", URL: "a"}
+
+	corrected := reportOf(true, requests("a", mergeStart, 100, 2))[key]
+	raw := reportOf(false, requests("a", mergeStart, 100, 2))[key]
+
+	cases := []struct {
+		name      string
+		merged    Item
+		corrected bool
+	}{
+		{"corrected runs", corrected.MergeRun(corrected), true},
+		{"corrected and raw runs", corrected.MergeRun(raw), false},
+		{"raw and corrected generators", raw.Merge(corrected), false},
+	}
+
+	for _, c := range cases {
+		if c.merged.LatencyCorrected != c.corrected {
+			t.Errorf("%s: latency_corrected %v, expected %v", c.name, c.merged.LatencyCorrected, c.corrected)
+		}
+
+		if !c.corrected {
+			if c.merged.CorrectedLatency != nil {
+				t.Errorf("%s: corrected_latency isn't omitted", c.name)
+			}
+
+			continue
+		}
+
+		if c.merged.CorrectedLatency.Count != c.merged.Latency.Count {
+			t.Errorf("%s: corrected latency of %d requests, latency of %d requests",
+				c.name, c.merged.CorrectedLatency.Count, c.merged.Latency.Count)
+		}
+	}
+}
@@ -4,19 +4,30 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cheggaaa/pb/v3"
 
+	"github.com/tagirmukail/ldtester/internal/auth"
 	"github.com/tagirmukail/ldtester/internal/config"
+	"github.com/tagirmukail/ldtester/internal/dialer"
+	"github.com/tagirmukail/ldtester/internal/h2conf"
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
+	"github.com/tagirmukail/ldtester/internal/resolver"
+	"github.com/tagirmukail/ldtester/internal/selfmon"
+	"github.com/tagirmukail/ldtester/internal/tlsconf"
+	"github.com/tagirmukail/ldtester/internal/tracing"
 	"github.com/tagirmukail/ldtester/internal/url_item"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/net/http2"
 )
 
 const (
@@ -26,8 +37,13 @@ const (
 
 	acceptHeader    = "accept"
 	userAgentHeader = "user-agent"
+
+	// round is late if it starts later than its intended start by this duration
+	scheduleTolerance = 5 * time.Millisecond
 )
 
+var errH2CProxy = errors.New("h2c can't be sent through http proxies, use the socks proxy")
+
 type Configuration struct {
 	MaxIdleConnPerHost  int           `json:"max_idle_conn_per_host"`
 	DisableCompression  bool          `json:"disable_compression"`
@@ -37,6 +53,42 @@ type Configuration struct {
 	Method              string        `json:"method"`
 	AcceptHeaderRequest string        `json:"accept_header_request"`
 	UserAgent           string        `json:"user_agent"`
+	RoundInterval       time.Duration `json:"round_interval"` // intended interval between rounds starts, 0 starts rounds back-to-back
+
+	// requests of the warm-up and the cool-down are excluded from statistics,
+	// the warm-up lasts until both its duration and requests count are reached
+	WarmUp         time.Duration `json:"warm_up"`
+	WarmUpRequests int           `json:"warm_up_requests"`
+	CoolDown       time.Duration `json:"cool_down"`
+
+	// the virtual user pauses for the think time after its request,
+	// its iteration of the request and the think time lasts at least Pacing
+	ThinkTime ThinkTime     `json:"think_time"`
+	Pacing    time.Duration `json:"pacing"`
+
+	// one load profile is distributed across urls by their weights instead of the load profile of every url
+	Mix bool `json:"mix"`
+	// urls of the same host share one load profile and one transport, the host is one capacity target
+	HostBudget bool `json:"host_budget"`
+
+	// handling of responses 429 and 503
+	RateLimit RateLimit `json:"rate_limit"`
+
+	TLS      tlsconf.Options   `json:"tls"`
+	Resolver resolver.Options  `json:"resolver"`
+	Proxy    proxyconf.Options `json:"proxy"`
+	Dial     dialer.Options    `json:"dial"`
+	HTTP2    h2conf.Options    `json:"http2"`
+	Auth     auth.Options      `json:"auth"`
+
+	// cookie jars of virtual users
+	Cookies Cookies `json:"cookies"`
+
+	// the redirect policy of requests
+	Redirects Redirects `json:"redirects"`
+
+	// receiving of response bodies: discard, skip
+	ResponseBody string `json:"response_body"`
 }
 
 // DefaultConfiguration sets default configuration for load testing
@@ -48,12 +100,23 @@ func DefaultConfiguration() Configuration {
 		UseHTTP2:           false,
 		Timeout:            3 * time.Second,
 		Method:             http.MethodGet,
+		RateLimit:          DefaultRateLimit(),
+		Redirects:          DefaultRedirects(),
+		ResponseBody:       ResponseBodyDiscard,
 	}
 
 	return conf
 }
 
-func FromGlobalConfig(loadTestConf config.LoadTest) Configuration {
+// Redacted returns the configuration with secrets replaced by "xxxxx", it is used in responses and runs history
+func (c Configuration) Redacted() Configuration {
+	c.Auth = c.Auth.Redacted()
+	c.Proxy = c.Proxy.Redacted()
+
+	return c
+}
+
+func FromGlobalConfig(loadTestConf config.LoadTest) (Configuration, error) {
 	conf := DefaultConfiguration()
 
 	if loadTestConf.Timeout > 0 {
@@ -84,7 +147,128 @@ func FromGlobalConfig(loadTestConf config.LoadTest) Configuration {
 		conf.MaxIdleConnPerHost = loadTestConf.MaxIdleConnPerHost
 	}
 
-	return conf
+	if loadTestConf.RoundInterval > 0 {
+		conf.RoundInterval = time.Duration(loadTestConf.RoundInterval) * time.Millisecond
+	}
+
+	if loadTestConf.WarmUp > 0 {
+		conf.WarmUp = time.Duration(loadTestConf.WarmUp) * time.Second
+	}
+
+	if loadTestConf.WarmUpRequests > 0 {
+		conf.WarmUpRequests = loadTestConf.WarmUpRequests
+	}
+
+	if loadTestConf.CoolDown > 0 {
+		conf.CoolDown = time.Duration(loadTestConf.CoolDown) * time.Second
+	}
+
+	if loadTestConf.Mix {
+		conf.Mix = true
+	}
+
+	if loadTestConf.HostBudget {
+		conf.HostBudget = true
+	}
+
+	if loadTestConf.Pacing > 0 {
+		conf.Pacing = time.Duration(loadTestConf.Pacing) * time.Millisecond
+	}
+
+	if loadTestConf.ResponseBody != "" {
+		conf.ResponseBody = loadTestConf.ResponseBody
+	}
+
+	conf.RateLimit = RateLimitFromConfig(conf.RateLimit, loadTestConf)
+	conf.TLS = tlsconf.FromConfig(loadTestConf.TLS)
+	conf.Resolver = resolver.FromConfig(loadTestConf.DNS)
+	conf.Proxy = proxyconf.FromConfig(loadTestConf.Proxy)
+	conf.Dial = dialer.FromConfig(loadTestConf.Dial)
+	conf.HTTP2 = h2conf.FromConfig(loadTestConf.HTTP2)
+	conf.Auth = auth.FromConfig(loadTestConf.Auth)
+	conf.Cookies = CookiesFromConfig(loadTestConf.Cookies)
+	conf.Redirects = RedirectsFromConfig(loadTestConf.Redirects)
+
+	err := conf.Resolver.Validate()
+	if err != nil {
+		return conf, err
+	}
+
+	err = conf.Dial.Validate()
+	if err != nil {
+		return conf, err
+	}
+
+	err = conf.HTTP2.Validate()
+	if err != nil {
+		return conf, err
+	}
+
+	err = conf.Cookies.Validate()
+	if err != nil {
+		return conf, err
+	}
+
+	err = conf.Redirects.Validate()
+	if err != nil {
+		return conf, err
+	}
+
+	err = ValidateResponseBody(conf.ResponseBody)
+	if err != nil {
+		return conf, err
+	}
+
+	err = conf.RateLimit.Validate()
+	if err != nil {
+		return conf, err
+	}
+
+	thinkTime, err := ThinkTimeFromConfig(loadTestConf)
+	if err != nil {
+		return conf, err
+	}
+
+	conf.ThinkTime = thinkTime
+
+	return conf, nil
+}
+
+// RateLimitFromConfig returns rl with values of the configuration which are set
+func RateLimitFromConfig(rl RateLimit, loadTestConf config.LoadTest) RateLimit {
+	if loadTestConf.RateLimitMode != "" {
+		rl.Mode = loadTestConf.RateLimitMode
+	}
+
+	if loadTestConf.RateLimitRetries > 0 {
+		rl.MaxRetries = loadTestConf.RateLimitRetries
+	}
+
+	if loadTestConf.RateLimitBackoff > 0 {
+		rl.Backoff = time.Duration(loadTestConf.RateLimitBackoff) * time.Millisecond
+	}
+
+	if loadTestConf.RateLimitMaxBackoff > 0 {
+		rl.MaxBackoff = time.Duration(loadTestConf.RateLimitMaxBackoff) * time.Millisecond
+	}
+
+	return rl
+}
+
+// ThinkTimeFromConfig returns the think time of the configuration, recorded think times are loaded from the file
+func ThinkTimeFromConfig(loadTestConf config.LoadTest) (ThinkTime, error) {
+	if loadTestConf.ThinkTimeFile == "" {
+		return ParseThinkTime(loadTestConf.ThinkTime)
+	}
+
+	samples, err := LoadThinkTimes(loadTestConf.ThinkTimeFile)
+	if err != nil {
+		return ThinkTime{}, err
+	}
+
+	tt := ThinkTime{Distribution: ThinkTimeRecorded, Samples: samples}
+
+	return tt, tt.Validate()
 }
 
 // Tester represents load testing struct
@@ -104,6 +288,32 @@ type Tester struct {
 	stopCh chan struct{}
 
 	report *report
+
+	observers []Observer
+
+	tracer *tracing.Tracer
+
+	control *control
+
+	noProgressBar bool
+
+	monitor   *selfmon.Monitor
+	generator *selfmon.Report
+
+	// the share of the load profile for distributed load testing, every round sends
+	// shareIndex part of shareCount parts of its requests
+	shareIndex int
+	shareCount int
+
+	verdictsMx sync.Mutex
+	verdicts   map[string]BudgetVerdict
+
+	backoff backoff // global backoff of rate limited requests
+
+	resolver *resolver.Resolver       // nil if the system resolver is used
+	auths    map[string]auth.Provider // providers of urls, urls with the same options share the provider
+	jars     *jars                    // nil if cookies are disabled
+	conns    connTracker
 }
 
 func New(shutdownCtx context.Context, cancel context.CancelFunc,
@@ -122,20 +332,70 @@ func New(shutdownCtx context.Context, cancel context.CancelFunc,
 			mx: sync.Mutex{},
 			m:  make(map[string]int),
 		},
+
+		control: newControl(),
 	}
 
 	t.reqResultCh = make(chan *requestResult, len(items)*2)
 
-	t.report = newReport(shutdownCtx, t.reqResultCh, conf.Timeout)
+	t.report = newReport(shutdownCtx, t.reqResultCh, conf.Timeout, conf.CoolDown, conf.RoundInterval > 0)
 
 	return t
 }
 
+// SetTracer sets the tracer of load test requests, it must be called before Run
+func (t *Tester) SetTracer(tracer *tracing.Tracer) {
+	t.tracer = tracer
+}
+
+// DisableProgressBar disables progress bars of the workers rounds, it must be called before Run
+func (t *Tester) DisableProgressBar() {
+	t.noProgressBar = true
+}
+
+// SetMonitor sets the monitor of the load generator resources usage, it must be called before Run
+func (t *Tester) SetMonitor(m *selfmon.Monitor) {
+	t.monitor = m
+}
+
 func (t *Tester) Run() {
 	if len(t.items) == 0 {
 		return
 	}
 
+	if t.monitor != nil {
+		t.monitor.Start(t.shutdownCtx, t.Stop)
+		defer func() { t.generator = t.monitor.Stop() }()
+	}
+
+	if !t.conf.Resolver.Empty() {
+		r, err := resolver.New(t.conf.Resolver)
+		if err != nil {
+			t.log.WithError(err).Error("setup resolver failed")
+			return
+		}
+
+		t.resolver = r
+	}
+
+	auths, err := t.authProviders()
+	if err != nil {
+		t.log.WithError(err).Error("setup auth failed")
+		return
+	}
+
+	t.auths = auths
+
+	if t.conf.Cookies.Enabled {
+		j, err := newJars(t.conf.Cookies)
+		if err != nil {
+			t.log.WithError(err).Error("setup cookies failed")
+			return
+		}
+
+		t.jars = j
+	}
+
 	go t.report.runReport()
 
 	t.runWorkers()
@@ -146,22 +406,57 @@ func (t *Tester) Stop() {
 	t.cancel()
 }
 
+// SetShare sets the share of the load profile, the tester sends only the part with index
+// of count equal parts of every round, it must be called before Run
+func (t *Tester) SetShare(index, count int) {
+	t.shareIndex = index
+	t.shareCount = count
+}
+
+// share returns the number of requests of the round for this tester
+func (t *Tester) share(numRequests int) int {
+	if t.shareCount <= 1 {
+		return numRequests
+	}
+
+	result := numRequests / t.shareCount
+	if t.shareIndex < numRequests%t.shareCount {
+		result++
+	}
+
+	return result
+}
+
 func (t *Tester) Report() map[Key]Item {
 	return t.report.globResult.GetResult()
 }
 
+// GeneratorReport returns resources usage of the load generator, nil if the monitor is not set
+func (t *Tester) GeneratorReport() *selfmon.Report {
+	if t.generator == nil && t.monitor != nil {
+		return t.monitor.Report()
+	}
+
+	return t.generator
+}
+
+// Snapshot returns the current report of the running load test
+func (t *Tester) Snapshot() map[Key]Item {
+	return t.report.globResult.Snapshot()
+}
+
+// finalize waits until the report processes results of all finished requests
 func (t *Tester) finalize() {
 	close(t.reqResultCh)
-	select {
-	case <-t.report.done:
-		return
-	case <-t.shutdownCtx.Done():
-		return
-	}
+	<-t.report.done
 }
 
-// runWorkers runs load testing workers for every url
+// runWorkers runs load testing workers for every url, or one worker for every budget of urls
 func (t *Tester) runWorkers() {
+	if t.conf.Mix || t.conf.HostBudget {
+		t.runBudgets()
+		return
+	}
 
 	wg := sync.WaitGroup{}
 
@@ -169,30 +464,17 @@ func (t *Tester) runWorkers() {
 		i := i
 		item := item
 
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-				ServerName:         item.Host,
-			},
-			MaxIdleConnsPerHost: t.conf.MaxIdleConnPerHost,
-			DisableCompression:  t.conf.DisableCompression,
-			DisableKeepAlives:   t.conf.DisableKeepAlive,
+		client, err := t.newClient(item)
+		if err != nil {
+			t.log.WithError(err).WithField("url", item.Url).Error("setup transport failed")
+			continue
 		}
 
-		if t.conf.UseHTTP2 {
-			_ = http2.ConfigureTransport(tr)
-		} else {
-			tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
-		}
-
-		client := &http.Client{
-			Transport: tr,
-			Timeout:   httpClientTimeout,
-		}
+		ctx := t.itemContext(Key{Host: item.Host, URL: item.Url})
 
 		wg.Add(1)
 		go func() {
-			t.runWorker(i, client, item)
+			t.runWorker(ctx, i, client, item)
 			wg.Done()
 		}()
 	}
@@ -200,11 +482,175 @@ func (t *Tester) runWorkers() {
 	wg.Wait()
 }
 
-// runWorker runs one worker for url
-func (t *Tester) runWorker(workerNum int, client *http.Client, item url_item.Item) {
+// ValidateTransport checks tls, proxy and auth options of the load test and its urls
+func ValidateTransport(conf Configuration, items []url_item.Item) error {
+	for _, item := range items {
+		tlsOpts := conf.TLS.Override(item.TLS)
+
+		_, err := tlsOpts.Config(item.Host)
+		if err != nil {
+			return fmt.Errorf("tls of %s: %w", item.Url, err)
+		}
+
+		err = tlsOpts.ValidateALPN(conf.UseHTTP2 || conf.HTTP2.H2C)
+		if err != nil {
+			return fmt.Errorf("tls of %s: %w", item.Url, err)
+		}
+
+		proxy := conf.Proxy.Override(item.Proxy)
+
+		err = proxy.Validate()
+		if err != nil {
+			return fmt.Errorf("proxy of %s: %w", item.Url, err)
+		}
+
+		// h2c connections are dialed by the transport, so they skip http proxies
+		if conf.HTTP2.H2C && proxy.HTTP() && strings.HasPrefix(strings.ToLower(item.Url), "http://") {
+			return fmt.Errorf("proxy of %s: %w", item.Url, errH2CProxy)
+		}
+
+		err = conf.Auth.Override(item.Auth).Validate()
+		if err != nil {
+			return fmt.Errorf("auth of %s: %w", item.Url, err)
+		}
+	}
+
+	return nil
+}
+
+// authProviders returns auth providers of urls, urls with the same options share the provider,
+// so oauth2 and jwt tokens are shared by their workers
+func (t *Tester) authProviders() (map[string]auth.Provider, error) {
+	result := make(map[string]auth.Provider)
+	shared := make(map[string]auth.Provider)
+
+	for _, item := range t.items {
+		opts := t.conf.Auth.Override(item.Auth)
+		if opts.Type == "" {
+			continue
+		}
+
+		key, err := jsoniter.MarshalToString(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		provider, ok := shared[key]
+		if !ok {
+			provider, err = auth.New(opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", item.Url, err)
+			}
+
+			shared[key] = provider
+		}
+
+		result[item.Url] = provider
+	}
+
+	return result, nil
+}
+
+// newClient returns the http client of the url, virtual users have their own transports
+// if they keep their local addresses
+func (t *Tester) newClient(item url_item.Item) (*http.Client, error) {
+	sources := t.conf.Dial.Sources()
+
+	transports := make(vuTransport, 0, sources)
+	for source := 0; source < sources; source++ {
+		tr, err := t.newTransport(item, source)
+		if err != nil {
+			return nil, err
+		}
+
+		transports = append(transports, tr)
+	}
+
+	var rt http.RoundTripper = transports[0]
+	if sources > 1 {
+		rt = transports
+	}
+
+	return &http.Client{
+		Transport:     rt,
+		CheckRedirect: t.checkRedirect,
+		Timeout:       httpClientTimeout,
+	}, nil
+}
+
+// newTransport returns the transport of the url which dials connections from the source of local addresses
+func (t *Tester) newTransport(item url_item.Item, source int) (http.RoundTripper, error) {
+	tlsConfig, err := t.conf.TLS.Override(item.TLS).Config(item.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	dial, err := t.conf.Dial.Dial(source)
+	if err != nil {
+		return nil, err
+	}
+
+	tr := &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConnsPerHost: t.conf.MaxIdleConnPerHost,
+		DisableCompression:  t.conf.DisableCompression,
+		DisableKeepAlives:   t.conf.DisableKeepAlive,
+	}
+
+	switch {
+	case t.resolver != nil && t.conf.Dial.UnixSocket == "" && dial != nil:
+		tr.DialContext = t.resolver.Dial(dial)
+	case t.resolver != nil && t.conf.Dial.UnixSocket == "":
+		tr.DialContext = t.resolver.DialContext
+	default:
+		tr.DialContext = dial
+	}
+
+	proxyDial := tr.DialContext
+	if proxyDial == nil {
+		proxyDial = (&net.Dialer{}).DialContext
+	}
+
+	err = t.conf.Proxy.Override(item.Proxy).Apply(tr, proxyDial)
+	if err != nil {
+		return nil, err
+	}
+
+	tr.DialContext = t.conns.dial(item.Host, tr.DialContext)
+
+	if t.conf.UseHTTP2 || t.conf.HTTP2.H2C {
+		return t.conf.HTTP2.Transport(tr)
+	}
+
+	tr.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+
+	return tr, nil
+}
+
+type vuKey struct{}
+
+// withVU returns the context of requests of the virtual user
+func withVU(ctx context.Context, vu int) context.Context {
+	return context.WithValue(ctx, vuKey{}, vu)
+}
+
+// vuTransport sends requests of virtual users by their own transports
+type vuTransport []http.RoundTripper
+
+func (v vuTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	vu, _ := req.Context().Value(vuKey{}).(int)
+
+	return v[vu%len(v)].RoundTrip(req)
+}
+
+// runWorker runs one worker for url, it is stopped when ctx is done
+func (t *Tester) runWorker(ctx context.Context, workerNum int, client *http.Client, item url_item.Item) {
 	var (
 		numRequests = 1
 		isHandler   bool
+
+		startedAt = time.Now()
+		sent      int // requests of all generators sent by the worker rounds
 	)
 
 	isHandlerVal := t.shutdownCtx.Value(IsHandlerKey)
@@ -212,9 +658,23 @@ func (t *Tester) runWorker(workerNum int, client *http.Client, item url_item.Ite
 		isHandler = isHandlerVal.(bool)
 	}
 
+	key := Key{Host: item.Host, URL: item.Url}
+	defer t.notifyConcurrency(key, 0)
+	defer t.warnLateRounds(key)
+
+	// the url may be repeated in the load test, so jars belong to the worker
+	jarGroup := fmt.Sprintf("%d %s", workerNum, item.Url)
+	ctx = withJarGroup(ctx, jarGroup)
+
+	// the intended start of the next round, rounds are paced by RoundInterval
+	var nextRound time.Time
+
 	for {
+		paused := t.Paused()
+		t.waitResumed(ctx)
+
 		select {
-		case <-t.shutdownCtx.Done():
+		case <-ctx.Done():
 			t.log.WithField("url", item.Url).WithField("worker_num", workerNum).Info("worker canceled")
 
 			return
@@ -229,14 +689,31 @@ func (t *Tester) runWorker(workerNum int, client *http.Client, item url_item.Ite
 			numRequests++
 		}
 
+		intendedStart, lag := t.roundStart(ctx, &nextRound, paused)
+		t.trackSchedule(key, lag)
+
+		r := round{
+			numRequests:   numRequests,
+			intendedStart: intendedStart,
+			warmUp:        time.Since(startedAt) < t.conf.WarmUp || sent < t.conf.WarmUpRequests,
+		}
+
+		sent += numRequests
+
+		roundRequests := t.share(numRequests)
+
+		t.notifyConcurrency(key, roundRequests)
+
 		var bar *pb.ProgressBar
-		if !isHandler {
+		if !isHandler && !t.noProgressBar {
 			t.log.WithField("url", item.Url).WithField("req_num", numRequests).Info("started")
-			bar = pb.StartNew(numRequests)
+			bar = pb.StartNew(roundRequests)
 		}
 
+		t.clearJars(jarGroup)
+
 		wg := sync.WaitGroup{}
-		for i := 0; i < numRequests; i++ {
+		for i := 0; i < roundRequests; i++ {
 			i := i
 
 			wg.Add(1)
@@ -250,7 +727,7 @@ func (t *Tester) runWorker(workerNum int, client *http.Client, item url_item.Ite
 				}()
 
 				select {
-				case <-t.shutdownCtx.Done():
+				case <-ctx.Done():
 					t.log.WithField("url", item.Url).WithField("worker_num", workerNum).
 						WithField("req_num", i).
 						Info("worker req num canceled")
@@ -259,7 +736,7 @@ func (t *Tester) runWorker(workerNum int, client *http.Client, item url_item.Ite
 				default:
 				}
 
-				t.doRequest(client, item, numRequests)
+				t.iterate(withVU(ctx, i), client, item, r)
 			}(&wg)
 		}
 
@@ -273,70 +750,328 @@ func (t *Tester) runWorker(workerNum int, client *http.Client, item url_item.Ite
 
 }
 
-// doRequest does request with analyze
-func (t *Tester) doRequest(client *http.Client, item url_item.Item, numRequests int) {
+// iterate runs one iteration of the virtual user: the request and the think time,
+// the iteration lasts at least the pacing
+func (t *Tester) iterate(ctx context.Context, client *http.Client, item url_item.Item, r round) {
+	iterationStart := time.Now()
+
+	client = t.withJar(ctx, client)
+
+	// the attempt is intended to be sent after the global backoff and the retry delay,
+	// so the corrected latency of the attempt doesn't include them and earlier attempts
+	for attempt := 0; ; attempt++ {
+		if t.backoff.wait(ctx) {
+			r.intendedStart = time.Now()
+		}
+
+		retryAfter, retry := t.doRequest(ctx, client, item, r, attempt)
+		if !retry {
+			break
+		}
+
+		wait(ctx, t.conf.RateLimit.retryDelay(attempt, retryAfter))
+
+		r.intendedStart = time.Now()
+	}
+
+	wait(ctx, t.conf.ThinkTime.Next())
+
+	if t.conf.Pacing > 0 {
+		wait(ctx, t.conf.Pacing-time.Since(iterationStart))
+	}
+}
+
+// roundStart waits for the intended start of the round by the rounds schedule, returns the intended start
+// and the lag of the actual start, nextRound is the intended start of the next round
+func (t *Tester) roundStart(ctx context.Context, nextRound *time.Time, paused bool) (time.Time, time.Duration) {
+	now := time.Now()
+	if t.conf.RoundInterval <= 0 {
+		return now, 0
+	}
+
+	// the schedule starts again after the pause
+	if nextRound.IsZero() || paused {
+		*nextRound = now
+	}
+
+	wait(ctx, time.Until(*nextRound))
+
+	intendedStart := *nextRound
+	*nextRound = nextRound.Add(t.conf.RoundInterval)
+
+	return intendedStart, time.Since(intendedStart)
+}
+
+// trackSchedule counts the late round of the url
+func (t *Tester) trackSchedule(key Key, lag time.Duration) {
+	if lag < scheduleTolerance {
+		return
+	}
+
+	t.report.globResult.ProcessItem(key, func(m map[Key]Item, i Item) {
+		i.LateRounds++
+
+		if lag.Seconds() > i.MaxScheduleLag {
+			i.MaxScheduleLag = lag.Seconds()
+		}
+
+		m[key] = i
+	})
+}
+
+// warnLateRounds warns that the corrected latency of the url differs from the raw one
+// because the generator couldn't keep up with the intended schedule
+func (t *Tester) warnLateRounds(key Key) {
+	item := t.report.globResult.Snapshot()[key]
+	if item.LateRounds == 0 {
+		return
+	}
+
+	t.log.WithField("url", key.URL).
+		WithField("late_rounds", item.LateRounds).
+		WithField("max_schedule_lag", item.MaxScheduleLag).
+		Warn("load generator couldn't keep up with the intended rounds schedule")
+}
+
+// round represents one round of the worker
+type round struct {
+	numRequests   int
+	intendedStart time.Time // the time when requests had to be sent by the schedule, or after the backoff of the attempt
+	warmUp        bool      // results of requests are excluded from statistics and don't stop the worker
+}
+
+// throttle stops the worker of the url after the round
+func (t *Tester) throttle(url string, r round) {
+	if r.warmUp {
+		return
+	}
+
+	t.throttlingChecker.Throttle(url, r.numRequests)
+}
+
+// doRequest does request with analyze, returns Retry-After of the rate limited response
+// and true if the request must be retried
+func (t *Tester) doRequest(ctx context.Context, client *http.Client, item url_item.Item, r round,
+	attempt int) (time.Duration, bool) {
 	var (
+		retryAfter time.Duration
+
 		now        = time.Now()
 		nowSince   = since(now)
 		dnsStart   time.Duration
 		startConn  time.Duration
 		reqStart   time.Duration
+		tlsStart   time.Duration
+		tlsTotal   time.Duration // handshakes with the proxy and the target
 		delayStart time.Duration
 		respStart  time.Duration
 
+		connectStart time.Duration
+		connectDone  time.Duration
+
+		hopHeaderBytes   int64 // header fields of the current hop of the request
+		hopPseudoHeaders bool
+
 		result = &requestResult{
 			urlKey: item.Url,
 			host:   item.Host,
 			offset: nowSince,
+			warmUp: r.warmUp,
 		}
+
+		key = Key{Host: item.Host, URL: item.Url}
 	)
 
-	req, _ := http.NewRequestWithContext(t.shutdownCtx, t.conf.Method, item.Url, nil)
+	t.notifyRequestStarted(key)
+
+	redirects := &redirectChain{hopStart: now}
+
+	req, _ := http.NewRequestWithContext(withRedirectChain(ctx, redirects), t.conf.Method, item.Url, nil)
 
 	req.Header.Set(acceptHeader, t.conf.AcceptHeaderRequest)
 	req.Header.Set(userAgentHeader, t.conf.UserAgent)
 
+	// gzip bodies are decompressed by readBody to count both their transferred and decompressed bytes
+	if !t.conf.DisableCompression {
+		req.Header.Set(acceptEncodingHeader, encodingGzip)
+	}
+
+	span := t.tracer.StartSpan("HTTP " + t.conf.Method)
+	defer span.End()
+
+	span.SetAttributes(
+		tracing.Attribute{Key: "http.method", Value: t.conf.Method},
+		tracing.Attribute{Key: "http.url", Value: item.Url},
+		tracing.Attribute{Key: "net.peer.name", Value: item.Host},
+		tracing.Attribute{Key: "ldtester.concurrency", Value: r.numRequests},
+	)
+	span.Inject(req.Header)
+
+	tunneled := t.conf.Proxy.Override(item.Proxy).Tunneled(req)
+
 	trace := &httptrace.ClientTrace{
 		DNSStart: func(info httptrace.DNSStartInfo) {
 			dnsStart = since(now)
+			span.AddEvent("dns_start")
 		},
 		DNSDone: func(info httptrace.DNSDoneInfo) {
 			result.dnsDuration = since(now) - dnsStart
+			span.AddEvent("dns_done")
+		},
+		ConnectStart: func(network, addr string) {
+			if connectStart == 0 {
+				connectStart = since(now)
+			}
+
+			span.AddEvent("connect_start", tracing.Attribute{Key: "net.peer.addr", Value: addr})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				connectDone = since(now)
+				result.connectDuration = connectDone - connectStart
+				result.connOpened = true
+			}
+
+			span.AddEvent("connect_done", tracing.Attribute{Key: "net.peer.addr", Value: addr})
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = since(now)
+			span.AddEvent("tls_handshake_start")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			handshake := since(now) - tlsStart
+			tlsTotal += handshake
+
+			if err == nil {
+				result.tlsHandshake = &tlsHandshake{
+					duration:    handshake,
+					version:     tlsconf.VersionName(state.Version),
+					cipherSuite: tls.CipherSuiteName(state.CipherSuite),
+					resumed:     state.DidResume,
+				}
+			}
+
+			span.AddEvent("tls_handshake_done")
 		},
 		GetConn: func(h string) {
 			startConn = since(now)
 		},
 		GotConn: func(info httptrace.GotConnInfo) {
+			if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+				result.remoteAddr = host
+			}
+
+			if len(t.conf.Dial.LocalAddrs) > 0 {
+				if host, _, err := net.SplitHostPort(info.Conn.LocalAddr().String()); err == nil {
+					result.localAddr = host
+				}
+			}
+
 			if info.Reused {
 				result.connDuration = since(now) - startConn
 			}
 
+			result.gotConn = true
+			result.newConn = !info.Reused
+			result.peakOpenConns = t.conns.peak(item.Host)
+
+			if info.WasIdle {
+				result.connIdle = info.IdleTime
+				result.connWasIdle = true
+			}
+
+			// the new tunneled connection is established after the handshake with the proxy
+			if !info.Reused && tunneled && connectDone > 0 {
+				result.proxyConnectDuration = since(now) - connectDone - tlsTotal
+			}
+
 			reqStart = since(now)
+			span.AddEvent("got_conn", tracing.Attribute{Key: "reused", Value: info.Reused})
+		},
+		WroteHeaderField: func(key string, value []string) {
+			hopHeaderBytes += headerFieldSize(key, value)
+
+			// http/2 sends the request line as pseudo-header fields
+			if strings.HasPrefix(key, ":") {
+				hopPseudoHeaders = true
+			}
+		},
+		WroteHeaders: func() {
+			if !hopPseudoHeaders {
+				result.payload.reqBytes += requestLineSize(req)
+			}
+
+			result.payload.reqBytes += hopHeaderBytes + 2
+			hopHeaderBytes, hopPseudoHeaders = 0, false
 		},
 		WroteRequest: func(info httptrace.WroteRequestInfo) {
 			result.reqDuration = since(now) - reqStart
 			delayStart = since(now)
+			span.AddEvent("wrote_request")
 		},
 		GotFirstResponseByte: func() {
 			result.delayDuration = since(now) - delayStart
 			respStart = since(now)
+			span.AddEvent("got_first_response_byte")
 		},
 	}
 
+	// the request is authenticated before the trace is attached, so token requests of the provider
+	// are not traced as the request
+	err := t.authenticate(item, req)
+
 	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
 
-	resp, err := client.Do(req)
+	var resp *http.Response
+
+	if err == nil {
+		resp, err = client.Do(req)
+	}
+
+	redirects.finish()
+
+	if err == nil {
+		// the body is received before the request is finished, so the receive phase is its transfer time
+		err = result.payload.readBody(resp, t.conf.ResponseBody)
+	}
+
+	result.authErr = errors.Is(err, auth.ErrAuth)
+	result.redirectHops = redirects.hops
+	result.redirectCodes = redirects.codes
+	result.redirectBlocked = redirects.blocked
+	result.redirectErr = errors.Is(err, errTooManyRedirects)
+
+	// the request failed before it got the connection: dns, dial, tls or proxy errors
+	result.connErr = err != nil && !result.gotConn && !result.authErr && !errors.Is(err, context.Canceled)
 	result.err = err
+	span.SetError(err)
 	switch {
 	case err == nil:
 		result.statusCode = resp.StatusCode
-		_ = resp.Body.Close()
+		result.proto = resp.Proto
+		span.SetAttributes(tracing.Attribute{Key: "http.status_code", Value: resp.StatusCode})
+		if resp.StatusCode >= http.StatusBadRequest {
+			span.SetError(errors.New(resp.Status))
+		}
+		if isRateLimited(resp.StatusCode) {
+			result.rateLimited = true
+			retryAfter = parseRetryAfter(resp.Header.Get(retryAfterHeader))
+		}
+	case errors.Is(err, context.Canceled):
+		// the worker is stopped, the request is not a part of the load test result
+	case result.authErr:
+		// the load of the url can't go on without the authentication
+		t.throttle(item.Url, r)
+		t.log.
+			WithError(err).
+			WithField("url", item.Url).
+			Error("authenticate request failed")
 	case errors.Is(err, context.DeadlineExceeded):
-		t.throttlingChecker.Throttle(item.Url, numRequests)
+		t.throttle(item.Url, r)
 	case os.IsTimeout(err):
-		t.throttlingChecker.Throttle(item.Url, numRequests)
+		t.throttle(item.Url, r)
 	default:
-		t.throttlingChecker.Throttle(item.Url, numRequests)
+		t.throttle(item.Url, r)
 		t.log.
 			WithError(err).
 			WithField("url", item.Url).
@@ -347,8 +1082,70 @@ func (t *Tester) doRequest(client *http.Client, item url_item.Item, numRequests
 	result.respDuration = finishedDuration - respStart
 
 	result.finishDuration = finishedDuration - nowSince
+	result.correctedDuration = time.Since(r.intendedStart)
+	result.finishedAt = time.Now()
+
+	if result.rateLimited {
+		result.retried = t.handleRateLimit(item, r, attempt, retryAfter)
+	}
+
+	t.notifyRequestFinished(key, result)
+
+	if errors.Is(err, context.Canceled) {
+		return 0, false
+	}
 
 	t.reqResultCh <- result
+
+	return retryAfter, result.retried
+}
+
+// authenticate authenticates the request by the provider of the url
+func (t *Tester) authenticate(item url_item.Item, req *http.Request) error {
+	provider, ok := t.auths[item.Url]
+	if !ok {
+		return nil
+	}
+
+	return provider.Authenticate(req)
+}
+
+// handleRateLimit handles the rate limited response by the rate limit mode, returns true if the request must be retried
+func (t *Tester) handleRateLimit(item url_item.Item, r round, attempt int, retryAfter time.Duration) bool {
+	switch t.conf.RateLimit.Mode {
+	case RateLimitCeiling:
+		t.throttle(item.Url, r)
+	case RateLimitBackoff:
+		if retryAfter <= 0 {
+			retryAfter = t.conf.RateLimit.Backoff
+		}
+
+		retryAfter = t.conf.RateLimit.limit(retryAfter)
+
+		if t.backoff.extend(retryAfter) {
+			t.log.WithField("url", item.Url).WithField("retry_after", retryAfter).
+				Info("request is rate limited, all requests back off")
+		}
+	case RateLimitRetry:
+		return attempt < t.conf.RateLimit.MaxRetries
+	}
+
+	return false
+}
+
+// wait waits for the duration or until ctx is done
+func wait(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
 }
 
 func since(t time.Time) time.Duration {
@@ -0,0 +1,156 @@
+package tester
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Think time distributions
+const (
+	ThinkTimeConstant    = "constant"
+	ThinkTimeUniform     = "uniform"
+	ThinkTimeNormal      = "normal"
+	ThinkTimeExponential = "exponential"
+	ThinkTimeRecorded    = "recorded"
+)
+
+var errInvalidThinkTime = errors.New("invalid think time")
+
+// ThinkTime represents the distribution of the pause of the virtual user after its request,
+// generated values are limited by Min and Max if they are set
+type ThinkTime struct {
+	Distribution string          `json:"distribution,omitempty"`
+	Mean         time.Duration   `json:"mean,omitempty"` // the value of the constant distribution
+	StdDev       time.Duration   `json:"std_dev,omitempty"`
+	Min          time.Duration   `json:"min,omitempty"`
+	Max          time.Duration   `json:"max,omitempty"`
+	Samples      []time.Duration `json:"samples,omitempty"` // recorded think times
+}
+
+// ParseThinkTime parses the think time spec in milliseconds: constant:500, uniform:100:900,
+// normal:500:100 (mean and std dev), exponential:500 (mean), empty spec disables think time
+func ParseThinkTime(spec string) (ThinkTime, error) {
+	if spec == "" {
+		return ThinkTime{}, nil
+	}
+
+	parts := strings.Split(spec, ":")
+
+	values := make([]time.Duration, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		ms, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return ThinkTime{}, fmt.Errorf("%w %q: %s", errInvalidThinkTime, spec, err)
+		}
+
+		values = append(values, time.Duration(ms*float64(time.Millisecond)))
+	}
+
+	tt := ThinkTime{Distribution: parts[0]}
+
+	switch {
+	case tt.Distribution == ThinkTimeConstant && len(values) == 1,
+		tt.Distribution == ThinkTimeExponential && len(values) == 1:
+		tt.Mean = values[0]
+	case tt.Distribution == ThinkTimeUniform && len(values) == 2:
+		tt.Min, tt.Max = values[0], values[1]
+	case tt.Distribution == ThinkTimeNormal && len(values) == 2:
+		tt.Mean, tt.StdDev = values[0], values[1]
+	default:
+		return ThinkTime{}, fmt.Errorf("%w %q, expected constant:ms, uniform:min:max, normal:mean:stddev "+
+			"or exponential:mean", errInvalidThinkTime, spec)
+	}
+
+	return tt, tt.Validate()
+}
+
+// LoadThinkTimes loads recorded think times from the file, one value in milliseconds per line
+func LoadThinkTimes(path string) ([]time.Duration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result := make([]time.Duration, 0)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		ms, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w in %s: %s", errInvalidThinkTime, path, err)
+		}
+
+		result = append(result, time.Duration(ms*float64(time.Millisecond)))
+	}
+
+	return result, scanner.Err()
+}
+
+// Validate checks parameters of the distribution
+func (tt ThinkTime) Validate() error {
+	if tt.Min < 0 || tt.Max < 0 || tt.Mean < 0 || tt.StdDev < 0 || tt.Max > 0 && tt.Min > tt.Max {
+		return fmt.Errorf("%w: negative values or min is greater than max", errInvalidThinkTime)
+	}
+
+	switch tt.Distribution {
+	case "", ThinkTimeConstant, ThinkTimeNormal, ThinkTimeExponential:
+		return nil
+	case ThinkTimeUniform:
+		if tt.Max == 0 {
+			return fmt.Errorf("%w: max of the uniform distribution is required", errInvalidThinkTime)
+		}
+
+		return nil
+	case ThinkTimeRecorded:
+		if len(tt.Samples) == 0 {
+			return fmt.Errorf("%w: recorded think times are empty", errInvalidThinkTime)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown distribution %q", errInvalidThinkTime, tt.Distribution)
+	}
+}
+
+// Next returns the next think time
+func (tt ThinkTime) Next() time.Duration {
+	var d time.Duration
+
+	switch tt.Distribution {
+	case ThinkTimeConstant:
+		d = tt.Mean
+	case ThinkTimeUniform:
+		d = tt.Min + time.Duration(rand.Int63n(int64(tt.Max-tt.Min)+1))
+	case ThinkTimeNormal:
+		d = tt.Mean + time.Duration(rand.NormFloat64()*float64(tt.StdDev))
+	case ThinkTimeExponential:
+		d = time.Duration(rand.ExpFloat64() * float64(tt.Mean))
+	case ThinkTimeRecorded:
+		d = tt.Samples[rand.Intn(len(tt.Samples))]
+	default:
+		return 0
+	}
+
+	if d < tt.Min {
+		d = tt.Min
+	}
+
+	if tt.Max > 0 && d > tt.Max {
+		d = tt.Max
+	}
+
+	return time.Duration(math.Max(float64(d), 0))
+}
@@ -0,0 +1,68 @@
+package tester
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
+	"github.com/tagirmukail/ldtester/internal/tlsconf"
+	"github.com/tagirmukail/ldtester/internal/url_item"
+)
+
+// TestValidateTransportH2CProxy checks that h2c of http urls is rejected with http proxies only
+func TestValidateTransportH2CProxy(t *testing.T) {
+	cases := []struct {
+		name  string
+		url   string
+		proxy proxyconf.Options
+		err   error
+	}{
+		{"http proxy", "http://backend.local", proxyconf.Options{URL: "http://proxy.local:3128"}, errH2CProxy},
+		{"proxy of the environment", "http://backend.local", proxyconf.Options{FromEnvironment: true}, errH2CProxy},
+		{"socks proxy", "http://backend.local", proxyconf.Options{URL: "socks5://proxy.local:1080"}, nil},
+		{"https url", "https://backend.local", proxyconf.Options{URL: "http://proxy.local:3128"}, nil},
+		{"no proxy", "http://backend.local", proxyconf.Options{}, nil},
+	}
+
+	for _, c := range cases {
+		conf := DefaultConfiguration()
+		conf.HTTP2.H2C = true
+
+		item := url_item.Item{Host: "backend.local", Url: c.url, Proxy: &c.proxy}
+
+		err := ValidateTransport(conf, []url_item.Item{item})
+		if !errors.Is(err, c.err) || c.err == nil && err != nil {
+			t.Errorf("%s: error %v, expected %v", c.name, err, c.err)
+		}
+	}
+}
+
+// TestBudgetClientsShareTransport checks that urls of the host budget share the client of the first url
+// without its own transport
+func TestBudgetClientsShareTransport(t *testing.T) {
+	conf := DefaultConfiguration()
+	conf.HostBudget = true
+
+	tester := &Tester{conf: conf}
+
+	items := []url_item.Item{
+		{Host: "backend.local", Url: "http://backend.local/a", TLS: &tlsconf.Options{ServerName: "other.local"}},
+		{Host: "backend.local", Url: "http://backend.local/b"},
+		{Host: "backend.local", Url: "http://backend.local/c"},
+		{Host: "backend.local", Url: "http://backend.local/d", Proxy: &proxyconf.Options{URL: "http://proxy.local:3128"}},
+		{Host: "backend.local", Url: "http://backend.local/e"},
+	}
+
+	clients, err := tester.budgetClients(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if clients[1] != clients[2] || clients[1] != clients[4] {
+		t.Fatal("urls without their own transports don't share one client")
+	}
+
+	if clients[0] == clients[1] || clients[3] == clients[1] || clients[0] == clients[3] {
+		t.Fatal("urls with their own transports share a client")
+	}
+}
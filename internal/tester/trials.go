@@ -0,0 +1,138 @@
+package tester
+
+import (
+	"math"
+	"sort"
+)
+
+// tCritical95 are two-sided 95% critical values of Student's t-distribution by degrees of freedom
+var tCritical95 = []float64{
+	0, 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+const zCritical95 = 1.96
+
+// Estimate represents the mean of the metric over trials with its 95% confidence interval
+type Estimate struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	CILow  float64 `json:"ci_low"`
+	CIHigh float64 `json:"ci_high"`
+	CV     float64 `json:"cv"` // coefficient of variation, std dev / mean
+}
+
+// TrialsSummary represents results of the url over repeated trials
+type TrialsSummary struct {
+	Trials            int      `json:"trials"`
+	RecommendReqCount Estimate `json:"recommend_req_count"`
+	Throughput        Estimate `json:"throughput"`         // requests per second
+	P50               Estimate `json:"p50"`                // sec
+	P95               Estimate `json:"p95"`                // sec
+	P99               Estimate `json:"p99"`                // sec
+	Unstable          []string `json:"unstable,omitempty"` // metrics with cv over the threshold
+}
+
+// Throughput returns requests per second in the measured window
+func (i Item) Throughput() float64 {
+	window := i.measuredDuration()
+	if window <= 0 {
+		return 0
+	}
+
+	return float64(i.TotalReqCount) / window
+}
+
+// SummarizeTrials returns estimates of every url over reports of trials,
+// metrics with the coefficient of variation over cvThreshold are marked unstable
+func SummarizeTrials(reports []map[string]Item, cvThreshold float64) map[string]TrialsSummary {
+	values := make(map[string]map[string][]float64)
+
+	for _, report := range reports {
+		for name, item := range report {
+			metrics, ok := values[name]
+			if !ok {
+				metrics = make(map[string][]float64)
+				values[name] = metrics
+			}
+
+			metrics["recommend_req_count"] = append(metrics["recommend_req_count"], float64(item.RecommendReqCount))
+			metrics["throughput"] = append(metrics["throughput"], item.Throughput())
+			metrics["p50"] = append(metrics["p50"], item.Percentile(50))
+			metrics["p95"] = append(metrics["p95"], item.Percentile(95))
+			metrics["p99"] = append(metrics["p99"], item.Percentile(99))
+		}
+	}
+
+	result := make(map[string]TrialsSummary, len(values))
+
+	for name, metrics := range values {
+		summary := TrialsSummary{
+			Trials:            len(metrics["throughput"]),
+			RecommendReqCount: estimate(metrics["recommend_req_count"]),
+			Throughput:        estimate(metrics["throughput"]),
+			P50:               estimate(metrics["p50"]),
+			P95:               estimate(metrics["p95"]),
+			P99:               estimate(metrics["p99"]),
+		}
+
+		for metric, e := range map[string]Estimate{
+			"recommend_req_count": summary.RecommendReqCount,
+			"throughput":          summary.Throughput,
+			"p50":                 summary.P50,
+			"p95":                 summary.P95,
+			"p99":                 summary.P99,
+		} {
+			if cvThreshold > 0 && e.CV > cvThreshold {
+				summary.Unstable = append(summary.Unstable, metric)
+			}
+		}
+
+		sort.Strings(summary.Unstable)
+
+		result[name] = summary
+	}
+
+	return result
+}
+
+// estimate returns the mean with the confidence interval of values
+func estimate(values []float64) Estimate {
+	n := len(values)
+	if n == 0 {
+		return Estimate{}
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	e := Estimate{Mean: sum / float64(n)}
+
+	if n > 1 {
+		var squares float64
+		for _, v := range values {
+			squares += (v - e.Mean) * (v - e.Mean)
+		}
+
+		e.StdDev = math.Sqrt(squares / float64(n-1))
+	}
+
+	t := zCritical95
+	if n-1 < len(tCritical95) {
+		t = tCritical95[n-1]
+	}
+
+	margin := t * e.StdDev / math.Sqrt(float64(n))
+
+	e.CILow = e.Mean - margin
+	e.CIHigh = e.Mean + margin
+
+	if e.Mean != 0 {
+		e.CV = e.StdDev / math.Abs(e.Mean)
+	}
+
+	return e
+}
@@ -0,0 +1,222 @@
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+const alpnH2 = "h2"
+
+var errALPNH2 = errors.New("alpn h2 requires http/2, enable UseHTTP2")
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Options represents tls options of target connections, by default certificates are not verified
+type Options struct {
+	Verify            *bool    `json:"verify,omitempty"`
+	CAFile            string   `json:"ca_file,omitempty"`   // pem bundle of trusted CAs, system CAs if empty
+	CertFile          string   `json:"cert_file,omitempty"` // client certificate for mTLS
+	KeyFile           string   `json:"key_file,omitempty"`
+	MinVersion        string   `json:"min_version,omitempty"` // 1.0, 1.1, 1.2, 1.3
+	MaxVersion        string   `json:"max_version,omitempty"`
+	CipherSuites      []string `json:"cipher_suites,omitempty"` // names of cipher suites of TLS 1.0-1.2
+	ServerName        string   `json:"server_name,omitempty"`   // SNI, the url host if empty
+	SessionResumption bool     `json:"session_resumption,omitempty"`
+	ALPN              []string `json:"alpn,omitempty"`
+}
+
+func FromConfig(cfg config.TLS) Options {
+	verify := cfg.Verify
+
+	return Options{
+		Verify:            &verify,
+		CAFile:            cfg.CAFile,
+		CertFile:          cfg.CertFile,
+		KeyFile:           cfg.KeyFile,
+		MinVersion:        cfg.MinVersion,
+		MaxVersion:        cfg.MaxVersion,
+		CipherSuites:      cfg.CipherSuites,
+		ServerName:        cfg.ServerName,
+		SessionResumption: cfg.SessionResumption,
+		ALPN:              cfg.ALPN,
+	}
+}
+
+// Override returns options with options of the url which are set
+func (o Options) Override(item *Options) Options {
+	if item == nil {
+		return o
+	}
+
+	if item.Verify != nil {
+		o.Verify = item.Verify
+	}
+
+	if item.CAFile != "" {
+		o.CAFile = item.CAFile
+	}
+
+	if item.CertFile != "" {
+		o.CertFile, o.KeyFile = item.CertFile, item.KeyFile
+	}
+
+	if item.MinVersion != "" {
+		o.MinVersion = item.MinVersion
+	}
+
+	if item.MaxVersion != "" {
+		o.MaxVersion = item.MaxVersion
+	}
+
+	if len(item.CipherSuites) > 0 {
+		o.CipherSuites = item.CipherSuites
+	}
+
+	if item.ServerName != "" {
+		o.ServerName = item.ServerName
+	}
+
+	if item.SessionResumption {
+		o.SessionResumption = true
+	}
+
+	if len(item.ALPN) > 0 {
+		o.ALPN = item.ALPN
+	}
+
+	return o
+}
+
+// ValidateALPN checks that h2 is negotiated only if the transport uses http/2,
+// the http/1.1 transport can't read frames of the negotiated http/2 connection
+func (o Options) ValidateALPN(http2 bool) error {
+	if http2 {
+		return nil
+	}
+
+	for _, proto := range o.ALPN {
+		if proto == alpnH2 {
+			return errALPNH2
+		}
+	}
+
+	return nil
+}
+
+// Config returns the tls config of connections to the host
+func (o Options) Config(host string) (*tls.Config, error) {
+	c := &tls.Config{
+		InsecureSkipVerify: o.Verify == nil || !*o.Verify,
+		ServerName:         host,
+		NextProtos:         o.ALPN,
+	}
+
+	if o.ServerName != "" {
+		c.ServerName = o.ServerName
+	}
+
+	if o.SessionResumption {
+		c.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+
+	var err error
+
+	c.MinVersion, err = version(o.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	c.MaxVersion, err = version(o.MaxVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	c.CipherSuites, err = cipherSuites(o.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", o.CAFile)
+		}
+	}
+
+	if o.CertFile != "" || o.KeyFile != "" {
+		if o.CertFile == "" || o.KeyFile == "" {
+			return nil, errors.New("both client certificate and key files are required")
+		}
+
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
+
+// VersionName returns the name of the tls version like in options
+func VersionName(v uint16) string {
+	for name, version := range versions {
+		if version == v {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("0x%04x", v)
+}
+
+func version(name string) (uint16, error) {
+	if name == "" {
+		return 0, nil
+	}
+
+	v, ok := versions[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown tls version %q, expected one of: 1.0, 1.1, 1.2, 1.3", name)
+	}
+
+	return v, nil
+}
+
+func cipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+
+	result := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
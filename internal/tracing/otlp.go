@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	spanKindClient  = 3
+	statusCodeOK    = 1
+	statusCodeError = 2
+)
+
+// runExporter exports queued spans by batches
+func (t *Tracer) runExporter() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		err := t.export(batch)
+		if err != nil {
+			t.log.WithError(err).WithField("spans", len(batch)).Error("export spans failed")
+		}
+
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.stopCh:
+			for {
+				select {
+				case s := <-t.queue:
+					batch = append(batch, s)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// export sends spans to the collector with OTLP/HTTP JSON encoding
+func (t *Tracer) export(spans []*Span) error {
+	body, err := jsoniter.Marshal(t.exportRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector responded %d: %s", resp.StatusCode, respBody)
+	}
+
+	return nil
+}
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+func (t *Tracer) exportRequest(spans []*Span) otlpExportRequest {
+	result := make([]otlpSpan, 0, len(spans))
+
+	for _, s := range spans {
+		s.mx.Lock()
+
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindClient,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+			Status:            otlpStatus{Code: statusCodeOK},
+		}
+
+		for _, e := range s.events {
+			span.Events = append(span.Events, otlpEvent{
+				TimeUnixNano: strconv.FormatInt(e.time.UnixNano(), 10),
+				Name:         e.name,
+				Attributes:   otlpAttributes(e.attrs),
+			})
+		}
+
+		if s.err != nil {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.err.Error()}
+		}
+
+		s.mx.Unlock()
+
+		result = append(result, span)
+	}
+
+	return otlpExportRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: otlpAttributes([]Attribute{{Key: "service.name", Value: t.serviceName}}),
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: defaultServiceName},
+				Spans: result,
+			}},
+		}},
+	}
+}
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	result := make([]otlpKeyValue, 0, len(attrs))
+
+	for _, attr := range attrs {
+		var value otlpAnyValue
+
+		switch v := attr.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int:
+			intValue := strconv.Itoa(v)
+			value.IntValue = &intValue
+		case int64:
+			intValue := strconv.FormatInt(v, 10)
+			value.IntValue = &intValue
+		case float64:
+			value.DoubleValue = &v
+		case bool:
+			value.BoolValue = &v
+		default:
+			stringValue := fmt.Sprint(v)
+			value.StringValue = &stringValue
+		}
+
+		result = append(result, otlpKeyValue{Key: attr.Key, Value: value})
+	}
+
+	return result
+}
@@ -0,0 +1,235 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tagirmukail/ldtester/internal/config"
+)
+
+const (
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+
+	defaultServiceName = "ldtester"
+
+	queueSize      = 4096
+	batchSize      = 512
+	exportInterval = 5 * time.Second
+	exportTimeout  = 10 * time.Second
+)
+
+// Tracer creates client spans for load test requests, propagates W3C trace context
+// and exports sampled spans to OTLP/HTTP collector. Nil Tracer is disabled.
+type Tracer struct {
+	log logrus.FieldLogger
+
+	endpoint    string
+	headers     map[string]string
+	propagate   bool
+	traceState  string
+	sampleRatio float64
+	serviceName string
+
+	client *http.Client
+
+	rndMx sync.Mutex
+	rnd   *mrand.Rand
+
+	queue   chan *Span
+	dropped uint64
+	stopCh  chan struct{}
+	done    chan struct{}
+}
+
+// New returns nil if tracing is disabled by the configuration
+func New(cfg config.Tracing, log logrus.FieldLogger) *Tracer {
+	if cfg.Endpoint == "" && !cfg.Propagate {
+		return nil
+	}
+
+	t := &Tracer{
+		log: log,
+
+		endpoint:    cfg.Endpoint,
+		headers:     cfg.Headers,
+		propagate:   cfg.Propagate,
+		traceState:  cfg.TraceState,
+		sampleRatio: cfg.SampleRatio,
+		serviceName: cfg.ServiceName,
+
+		client: &http.Client{Timeout: exportTimeout},
+
+		rnd: mrand.New(mrand.NewSource(time.Now().UnixNano())),
+
+		queue:  make(chan *Span, queueSize),
+		stopCh: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if t.serviceName == "" {
+		t.serviceName = defaultServiceName
+	}
+
+	t.sampleRatio = math.Max(0, math.Min(1, t.sampleRatio))
+
+	go t.runExporter()
+
+	return t
+}
+
+// Shutdown exports queued spans and stops the exporter
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+
+	close(t.stopCh)
+	<-t.done
+
+	dropped := atomic.LoadUint64(&t.dropped)
+	if dropped > 0 {
+		t.log.WithField("dropped_spans", dropped).Warn("spans export queue was full")
+	}
+}
+
+// StartSpan starts the client span, it returns nil if the tracer is disabled
+func (t *Tracer) StartSpan(name string) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{
+		tracer:  t,
+		name:    name,
+		start:   time.Now(),
+		sampled: t.sample(),
+	}
+
+	_, _ = rand.Read(s.traceID[:])
+	_, _ = rand.Read(s.spanID[:])
+
+	return s
+}
+
+func (t *Tracer) sample() bool {
+	if t.endpoint == "" {
+		return false
+	}
+
+	t.rndMx.Lock()
+	defer t.rndMx.Unlock()
+
+	return t.rnd.Float64() < t.sampleRatio
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	select {
+	case t.queue <- s:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+// Attribute represents span or event attribute, value is string, int, float64 or bool
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+type event struct {
+	name  string
+	time  time.Time
+	attrs []Attribute
+}
+
+// Span represents client span of one request, nil Span is disabled
+type Span struct {
+	tracer *Tracer
+
+	traceID [16]byte
+	spanID  [8]byte
+	sampled bool
+
+	name  string
+	start time.Time
+	end   time.Time
+
+	mx     sync.Mutex
+	attrs  []Attribute
+	events []event
+	err    error
+}
+
+// Inject sets W3C traceparent and tracestate headers if propagation is enabled
+func (s *Span) Inject(h http.Header) {
+	if s == nil || !s.tracer.propagate {
+		return
+	}
+
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+
+	h.Set(traceParentHeader, "00-"+hex.EncodeToString(s.traceID[:])+"-"+hex.EncodeToString(s.spanID[:])+"-"+flags)
+
+	if s.tracer.traceState != "" {
+		h.Set(traceStateHeader, s.tracer.traceState)
+	}
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil || !s.sampled {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// AddEvent adds the event with the current time, it is safe for concurrent use
+func (s *Span) AddEvent(name string, attrs ...Attribute) {
+	if s == nil || !s.sampled {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.events = append(s.events, event{name: name, time: time.Now(), attrs: attrs})
+}
+
+func (s *Span) SetError(err error) {
+	if s == nil || !s.sampled {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.err = err
+}
+
+// End finishes the span and queues it for export if it is sampled
+func (s *Span) End() {
+	if s == nil || !s.sampled {
+		return
+	}
+
+	s.mx.Lock()
+	s.end = time.Now()
+	s.mx.Unlock()
+
+	s.tracer.enqueue(s)
+}
@@ -0,0 +1,291 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/tagirmukail/ldtester/internal/histogram"
+	"github.com/tagirmukail/ldtester/internal/tester"
+)
+
+const (
+	historySize   = 60 // seconds of the per second history
+	statsWindow   = 10 // seconds for percentiles and error rate
+	sparklineSize = 30 // seconds of the latency history
+	logLinesCount = 6
+)
+
+var ErrNotTerminal = errors.New("tui requires a terminal")
+
+// Controller represents controls of the running load test
+type Controller interface {
+	Pause()
+	Resume()
+	Paused() bool
+	StopItem(key tester.Key)
+	Stop()
+}
+
+// second represents requests of the url finished in one second
+type second struct {
+	stamp    int64
+	requests uint64
+	errors   uint64
+	latency  *histogram.Histogram
+}
+
+type row struct {
+	key         tester.Key
+	concurrency int
+	requests    uint64
+	errors      uint64
+	started     bool
+	stopped     bool // stopped by the user
+	history     [historySize]second
+}
+
+// at returns the history second of the unix time, it resets the old second
+func (r *row) at(stamp int64) *second {
+	s := &r.history[stamp%historySize]
+	if s.stamp != stamp {
+		*s = second{stamp: stamp, latency: histogram.New()}
+	}
+
+	return s
+}
+
+// Dashboard represents full-screen terminal dashboard of the load test,
+// it implements tester.Observer and io.Writer for the log output
+type Dashboard struct {
+	mx       sync.Mutex
+	rows     []*row
+	index    map[tester.Key]*row
+	selected int
+	stopping bool
+
+	logs    []string
+	logTail []byte
+
+	start time.Time
+}
+
+// New returns the dashboard with rows of urls in the keys order
+func New(keys []tester.Key) (*Dashboard, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil, ErrNotTerminal
+	}
+
+	d := &Dashboard{
+		index: make(map[tester.Key]*row, len(keys)),
+		start: time.Now(),
+	}
+
+	for _, key := range keys {
+		if _, ok := d.index[key]; ok {
+			continue
+		}
+
+		r := &row{key: key}
+		d.rows = append(d.rows, r)
+		d.index[key] = r
+	}
+
+	return d, nil
+}
+
+func (d *Dashboard) ConcurrencyChanged(key tester.Key, concurrency int) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	r := d.row(key)
+	r.concurrency = concurrency
+	r.started = true
+}
+
+func (d *Dashboard) RequestStarted(tester.Key) {}
+
+func (d *Dashboard) RequestFinished(key tester.Key, sample tester.Sample) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if errors.Is(sample.Err, context.Canceled) {
+		return // the url is stopped
+	}
+
+	r := d.row(key)
+	s := r.at(time.Now().Unix())
+
+	r.requests++
+	s.requests++
+
+	if sample.Err != nil {
+		r.errors++
+		s.errors++
+		return
+	}
+
+	s.latency.Record(sample.Duration)
+}
+
+// Write keeps the last log lines to show them under the table
+func (d *Dashboard) Write(p []byte) (int, error) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.logTail = append(d.logTail, p...)
+
+	for {
+		i := bytes.IndexByte(d.logTail, '\n')
+		if i < 0 {
+			break
+		}
+
+		d.logs = append(d.logs, strings.TrimSpace(string(d.logTail[:i])))
+		d.logTail = d.logTail[i+1:]
+	}
+
+	if len(d.logs) > logLinesCount {
+		d.logs = d.logs[len(d.logs)-logLinesCount:]
+	}
+
+	return len(p), nil
+}
+
+// row returns the row of the url, d.mx must be locked
+func (d *Dashboard) row(key tester.Key) *row {
+	r, ok := d.index[key]
+	if !ok {
+		r = &row{key: key}
+		d.rows = append(d.rows, r)
+		d.index[key] = r
+	}
+
+	return r
+}
+
+// rowStats represents calculated values of the row for rendering
+type rowStats struct {
+	url         string
+	state       string
+	concurrency int
+	rps         float64
+	p50         time.Duration
+	p95         time.Duration
+	p99         time.Duration
+	errorRate   float64
+	sparkline   []time.Duration // mean latency of every second, oldest first
+	selected    bool
+}
+
+// stats calculates values of all rows at the time now
+func (d *Dashboard) stats(now time.Time) []rowStats {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	current := now.Unix()
+	result := make([]rowStats, 0, len(d.rows))
+
+	for i, r := range d.rows {
+		stats := rowStats{
+			url:         r.key.URL,
+			concurrency: r.concurrency,
+			selected:    i == d.selected,
+		}
+
+		switch {
+		case r.stopped:
+			stats.state = "stopped"
+		case !r.started:
+			stats.state = "waiting"
+		case r.concurrency == 0:
+			stats.state = "finished"
+		default:
+			stats.state = "running"
+		}
+
+		// rps of the last full second
+		if s := r.history[(current-1)%historySize]; s.stamp == current-1 {
+			stats.rps = float64(s.requests)
+		}
+
+		window := histogram.New()
+
+		var requests, errCount uint64
+		for stamp := current - statsWindow + 1; stamp <= current; stamp++ {
+			s := r.history[stamp%historySize]
+			if s.stamp != stamp {
+				continue
+			}
+
+			requests += s.requests
+			errCount += s.errors
+			window.Merge(s.latency)
+		}
+
+		stats.p50 = window.Quantile(0.5)
+		stats.p95 = window.Quantile(0.95)
+		stats.p99 = window.Quantile(0.99)
+
+		if requests > 0 {
+			stats.errorRate = float64(errCount) / float64(requests) * 100
+		}
+
+		for stamp := current - sparklineSize; stamp < current; stamp++ {
+			var mean time.Duration
+			if s := r.history[stamp%historySize]; s.stamp == stamp {
+				mean = s.latency.Mean()
+			}
+
+			stats.sparkline = append(stats.sparkline, mean)
+		}
+
+		result = append(result, stats)
+	}
+
+	return result
+}
+
+func (d *Dashboard) moveSelection(delta int) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	d.selected += delta
+
+	if d.selected < 0 {
+		d.selected = 0
+	}
+
+	if d.selected >= len(d.rows) {
+		d.selected = len(d.rows) - 1
+	}
+}
+
+// stopSelected stops the worker of the selected url
+func (d *Dashboard) stopSelected(c Controller) {
+	d.mx.Lock()
+	if d.selected < 0 || d.selected >= len(d.rows) {
+		d.mx.Unlock()
+		return
+	}
+
+	r := d.rows[d.selected]
+	r.stopped = true
+	d.mx.Unlock()
+
+	c.StopItem(r.key)
+}
+
+func (d *Dashboard) stopAll(c Controller) {
+	d.mx.Lock()
+	d.stopping = true
+	d.mx.Unlock()
+
+	c.Stop()
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package tui
+
+import (
+	"os"
+	"syscall"
+)
+
+// openStdin returns the non-blocking duplicate of stdin, its reads are interrupted by deadlines,
+// release restores the blocking mode of stdin and closes the duplicate
+func openStdin() (*os.File, func()) {
+	fd, err := syscall.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return os.Stdin, func() {}
+	}
+
+	err = syscall.SetNonblock(fd, true)
+	if err != nil {
+		_ = syscall.Close(fd)
+		return os.Stdin, func() {}
+	}
+
+	in := os.NewFile(uintptr(fd), "stdin")
+
+	return in, func() {
+		_ = syscall.SetNonblock(fd, false)
+		_ = in.Close()
+	}
+}
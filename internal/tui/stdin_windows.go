@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package tui
+
+import (
+	"os"
+)
+
+// openStdin returns stdin, deadlines of console reads are not supported on windows,
+// so the reader of keys is stopped after the next key
+func openStdin() (*os.File, func()) {
+	return os.Stdin, func() {}
+}
@@ -0,0 +1,288 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	refreshInterval = 500 * time.Millisecond
+
+	defaultWidth = 120
+	minURLWidth  = 20
+
+	// fixed columns width of the table without the url column
+	columnsWidth = 9 + 6 + 8 + 3*9 + 7 + 1 + sparklineSize
+
+	clearLine       = "\x1b[K"
+	clearScreenTail = "\x1b[J"
+	cursorHome      = "\x1b[H"
+	enterScreen     = "\x1b[?1049h\x1b[?25l" // alternate screen and hidden cursor
+	leaveScreen     = "\x1b[?25h\x1b[?1049l"
+	reverseVideo    = "\x1b[7m"
+	resetVideo      = "\x1b[0m"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+type key int
+
+const (
+	keyUnknown key = iota
+	keyUp
+	keyDown
+	keyPause
+	keyStopURL
+	keyQuit
+)
+
+// Run shows the dashboard until done is closed, keyboard controls manage the load test by c
+func (d *Dashboard) Run(c Controller, done <-chan struct{}) error {
+	fd := int(os.Stdin.Fd())
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		fmt.Print(leaveScreen)
+		_ = term.Restore(fd, state)
+	}()
+
+	fmt.Print(enterScreen)
+
+	in, release := openStdin()
+
+	// without read deadlines the reader of keys stops after the next key
+	deadlines := in.SetReadDeadline(time.Time{}) == nil
+
+	keys := make(chan key)
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		readKeys(in, keys, stop)
+	}()
+
+	// the terminal is restored after the reader of keys is stopped
+	defer func() {
+		close(stop)
+
+		if deadlines {
+			<-stopped
+			release()
+		}
+	}()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		d.render(c)
+
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		case k := <-keys:
+			switch k {
+			case keyUp:
+				d.moveSelection(-1)
+			case keyDown:
+				d.moveSelection(1)
+			case keyPause:
+				if c.Paused() {
+					c.Resume()
+				} else {
+					c.Pause()
+				}
+			case keyStopURL:
+				d.stopSelected(c)
+			case keyQuit:
+				d.stopAll(c)
+			}
+		}
+	}
+}
+
+// readKeys reads keys from the terminal in the raw mode until stop is closed, the read deadline
+// interrupts the wait for the key to check stop
+func readKeys(in *os.File, keys chan<- key, stop <-chan struct{}) {
+	buf := make([]byte, 8)
+
+	for {
+		_ = in.SetReadDeadline(time.Now().Add(refreshInterval))
+
+		n, err := in.Read(buf)
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+
+		if err != nil {
+			return
+		}
+
+		in := string(buf[:n])
+
+		var k key
+		switch {
+		case in == "\x1b[A" || in == "k":
+			k = keyUp
+		case in == "\x1b[B" || in == "j":
+			k = keyDown
+		case in == "p" || in == " ":
+			k = keyPause
+		case in == "s":
+			k = keyStopURL
+		case in == "q" || in == "\x03": // ctrl+c does not send the signal in the raw mode
+			k = keyQuit
+		default:
+			continue
+		}
+
+		select {
+		case keys <- k:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (d *Dashboard) render(c Controller) {
+	now := time.Now()
+
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = defaultWidth
+	}
+
+	urlWidth := width - columnsWidth
+	if urlWidth < minURLWidth {
+		urlWidth = minURLWidth
+	}
+
+	d.mx.Lock()
+	stopping := d.stopping
+	logs := append([]string{}, d.logs...)
+	d.mx.Unlock()
+
+	status := "RUNNING"
+	switch {
+	case stopping:
+		status = "STOPPING"
+	case c.Paused():
+		status = "PAUSED"
+	}
+
+	b := strings.Builder{}
+	b.WriteString(cursorHome)
+
+	line := func(format string, args ...interface{}) {
+		b.WriteString(fmt.Sprintf(format, args...))
+		b.WriteString(clearLine + "\r\n")
+	}
+
+	line("ldtester  elapsed %s  %s", now.Sub(d.start).Truncate(time.Second), status)
+	line("")
+	line("%-*s %8s %5s %7s %8s %8s %8s %6s  %s",
+		urlWidth, "URL", "STATE", "CONC", "RPS", "P50", "P95", "P99", "ERR%", "LATENCY")
+
+	for _, s := range d.stats(now) {
+		row := fmt.Sprintf("%-*s %8s %5d %7.1f %8s %8s %8s %6.1f  %s",
+			urlWidth, truncate(s.url, urlWidth), s.state, s.concurrency, s.rps,
+			formatDuration(s.p50), formatDuration(s.p95), formatDuration(s.p99), s.errorRate,
+			sparkline(s.sparkline))
+
+		if s.selected {
+			row = reverseVideo + row + resetVideo
+		}
+
+		line("%s", row)
+	}
+
+	line("")
+
+	for _, l := range logs {
+		line("%s", truncate(l, width))
+	}
+
+	line("")
+	line("↑/↓ select url   p pause/resume   s stop url   q stop run")
+
+	b.WriteString(clearScreenTail)
+
+	fmt.Print(b.String())
+}
+
+// sparkline scales values between min and max of non-zero values, zero values are blank
+func sparkline(values []time.Duration) string {
+	var min, max time.Duration
+
+	for _, v := range values {
+		if v == 0 {
+			continue
+		}
+
+		if min == 0 || v < min {
+			min = v
+		}
+
+		if v > max {
+			max = v
+		}
+	}
+
+	result := make([]rune, 0, len(values))
+
+	for _, v := range values {
+		if v == 0 {
+			result = append(result, ' ')
+			continue
+		}
+
+		idx := len(sparks) - 1
+		if max > min {
+			idx = int(math.Round(float64(v-min) / float64(max-min) * float64(len(sparks)-1)))
+		}
+
+		result = append(result, sparks[idx])
+	}
+
+	return string(result)
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "-"
+	case d < time.Millisecond:
+		return fmt.Sprintf("%dµs", d.Microseconds())
+	case d < time.Second:
+		return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+	default:
+		return fmt.Sprintf("%.2fs", d.Seconds())
+	}
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+
+	return string(r[:width-1]) + "…"
+}
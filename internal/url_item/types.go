@@ -1,6 +1,38 @@
 package url_item
 
+import (
+	"github.com/tagirmukail/ldtester/internal/auth"
+	"github.com/tagirmukail/ldtester/internal/proxyconf"
+	"github.com/tagirmukail/ldtester/internal/tlsconf"
+)
+
 type Item struct {
-	Host string `json:"-"`
-	Url  string `json:"url"`
+	Host   string             `json:"-"`
+	Url    string             `json:"url"`
+	Weight float64            `json:"weight,omitempty"` // share of the url in the weighted mix, 1 if it is not set
+	TLS    *tlsconf.Options   `json:"tls,omitempty"`    // tls options of the url over options of the load test
+	Proxy  *proxyconf.Options `json:"proxy,omitempty"`  // proxy of the url instead of the proxy of the load test
+	Auth   *auth.Options      `json:"auth,omitempty"`   // authentication of the url instead of the one of the load test
+}
+
+// Redacted returns copies of items with secrets of auth and proxies replaced by "xxxxx",
+// they are used in responses and runs history
+func Redacted(items []Item) []Item {
+	result := make([]Item, 0, len(items))
+
+	for _, item := range items {
+		if item.Auth != nil {
+			a := item.Auth.Redacted()
+			item.Auth = &a
+		}
+
+		if item.Proxy != nil {
+			p := item.Proxy.Redacted()
+			item.Proxy = &p
+		}
+
+		result = append(result, item)
+	}
+
+	return result
 }